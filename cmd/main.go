@@ -1,27 +1,37 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
 
 	skrsvc "github.com/kyma-incubator/metris/pkg/skr/svc"
 
+	skrclientpool "github.com/kyma-incubator/metris/pkg/skr/clientpool"
+
+	skrpv "github.com/kyma-incubator/metris/pkg/skr/pv"
 	skrpvc "github.com/kyma-incubator/metris/pkg/skr/pvc"
 
 	skrnode "github.com/kyma-incubator/metris/pkg/skr/node"
 
 	"github.com/kyma-incubator/metris/pkg/keb"
 
+	"github.com/kyma-incubator/metris/pkg/aggregation"
 	"github.com/kyma-incubator/metris/pkg/edp"
+	"github.com/kyma-incubator/metris/pkg/outbox"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/gorilla/mux"
 
+	"github.com/kyma-incubator/metris/pkg/admin"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
 	"github.com/kyma-incubator/metris/pkg/service"
 
+	"github.com/kyma-incubator/metris/pkg/filewatch"
+	gardenerkubeconfig "github.com/kyma-incubator/metris/pkg/gardener/kubeconfig"
 	gardenersecret "github.com/kyma-incubator/metris/pkg/gardener/secret"
 	gardenershoot "github.com/kyma-incubator/metris/pkg/gardener/shoot"
 	metrisprocess "github.com/kyma-incubator/metris/pkg/process"
@@ -63,17 +73,39 @@ func main() {
 		log.Fatalf("failed to generate client for gardener secrets: %v", err)
 	}
 
+	// Keep the shoot kubeconfigs up-to-date when they get rotated
+	kubeconfigManager := gardenerkubeconfig.NewManager(secretClient, log)
+	go kubeconfigManager.Watch(context.Background())
+
 	shootClient, err := gardenershoot.NewClient(opts)
 	if err != nil {
 		log.Fatalf("failed to generate client for gardener shoots: %v", err)
 	}
 
+	// Rebuild the gardener clients when the mounted kubeconfig of the Gardener MPS cluster gets rotated
+	gardenerKubeconfigWatcher, err := filewatch.NewWatcher(opts.GardenerSecretPath, filewatch.DefaultInterval, log)
+	if err != nil {
+		log.Fatalf("failed to watch gardener kubeconfig: %v", err)
+	}
+	gardenerKubeconfigWatcher.OnChange(func(_ []byte) {
+		if err := secretClient.Reload(opts); err != nil {
+			log.Errorf("failed to reload client for gardener secrets: %v", err)
+		}
+		if err := shootClient.Reload(opts); err != nil {
+			log.Errorf("failed to reload client for gardener shoots: %v", err)
+		}
+	})
+	go gardenerKubeconfigWatcher.Run(context.Background())
+
 	// Create a client for KEB communication
 	kebConfig := new(keb.Config)
 	if err := envconfig.Process("", kebConfig); err != nil {
 		log.Fatalf("failed to load KEB config: %s", err)
 	}
-	kebClient := keb.NewClient(kebConfig, log)
+	kebClient, err := keb.NewClient(kebConfig, log)
+	if err != nil {
+		log.Fatalf("failed to create KEB client: %v", err)
+	}
 	log.Debugf("keb config: %v", kebConfig)
 
 	// Creating cache with no expiration and the data will never be cleaned up
@@ -84,24 +116,64 @@ func main() {
 	if err := envconfig.Process("", edpConfig); err != nil {
 		log.Fatalf("failed to load EDP config: %s", err)
 	}
-	edpClient := edp.NewClient(edpConfig, log)
+	edpClient, err := edp.NewClient(edpConfig, log)
+	if err != nil {
+		log.Fatalf("failed to create EDP client: %v", err)
+	}
+
+	// Send the event streams of multiple tenants in one request if enabled.
+	// The batch endpoint is experimental, so batching is off by default.
+	var edpBatcher *edp.Batcher
+	if edpConfig.BatchEnabled {
+		log.Warnf("batching is experimental and needs a server which implements the batch contract of metris, EDP does not document one")
+		edpBatcher = edp.NewBatcher(edpClient, edpConfig.BatchSize, edpConfig.BatchInterval, log)
+	}
+
+	// Keep the events on disk till EDP accepted them
+	var edpOutbox *outbox.Outbox
+	if opts.OutboxDir != "" {
+		edpOutbox, err = outbox.New(opts.OutboxDir, opts.OutboxMaxEvents, opts.OutboxReplayTime, log)
+		if err != nil {
+			log.Fatalf("failed to create outbox for EDP: %v", err)
+		}
+	}
+
+	// Aggregate the snapshots into the time-weighted usage of the tenants
+	var usageAggregator *aggregation.Aggregator
+	if opts.AggregationWindow > 0 {
+		usageAggregator, err = aggregation.New(opts.AggregationWindow, opts.AggregationMaxGap, opts.AggregationDir, log)
+		if err != nil {
+			log.Fatalf("failed to create usage aggregator: %v", err)
+		}
+	}
 
 	queue := workqueue.NewDelayingQueue()
 
+	// Share the clients of a Kyma cluster across all resource types and scrapes
+	skrClientPool := skrclientpool.NewPool(opts.SKRClientIdleTime)
+	go skrClientPool.Run(context.Background())
+
 	metrisProcess := metrisprocess.Process{
-		KEBClient:       kebClient,
-		ShootClient:     shootClient,
-		SecretClient:    secretClient,
-		EDPClient:       edpClient,
-		Logger:          log,
-		Providers:       publicCloudSpecs,
-		Cache:           cache,
-		ScrapeInterval:  opts.ScrapeInterval,
-		Queue:           queue,
-		WorkersPoolSize: opts.WorkerPoolSize,
-		NodeConfig:      skrnode.Config{},
-		PVCConfig:       skrpvc.Config{},
-		SvcConfig:       skrsvc.Config{},
+		KEBClient:          kebClient,
+		ShootClient:        shootClient,
+		KubeconfigManager:  kubeconfigManager,
+		EDPClient:          edpClient,
+		Outbox:             edpOutbox,
+		Aggregator:         usageAggregator,
+		Batcher:            edpBatcher,
+		Logger:             log,
+		Providers:          publicCloudSpecs,
+		Cache:              cache,
+		Statuses:           metriscache.NewStatusStore(),
+		ScrapeInterval:     opts.ScrapeInterval,
+		RuntimeGracePeriod: opts.RuntimeGracePeriod,
+		LifecycleDir:       opts.LifecycleDir,
+		Queue:              queue,
+		WorkersPoolSize:    opts.WorkerPoolSize,
+		NodeConfig:         skrnode.Config{Pool: skrClientPool},
+		PVCConfig:          skrpvc.Config{Pool: skrClientPool},
+		PVConfig:           skrpv.Config{Pool: skrClientPool},
+		SvcConfig:          skrsvc.Config{Pool: skrClientPool},
 	}
 
 	// Start execution
@@ -118,6 +190,14 @@ func main() {
 	})
 	router.Path(metricsPath).Handler(promhttp.Handler())
 
+	adminHandler := admin.Handler{
+		Statuses:  metrisProcess,
+		Rescraper: metrisProcess,
+		Token:     cfg.AdminToken,
+		Logger:    log,
+	}
+	adminHandler.Register(router)
+
 	metrisSvr := service.Server{
 		Addr:   fmt.Sprintf(":%d", opts.ListenAddr),
 		Logger: log,
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/admin"
+)
+
+const (
+	statusCmd   = "status"
+	rescrapeCmd = "rescrape"
+)
+
+const usage = `metrisctl talks to the admin endpoints of a running metris.
+
+Usage:
+  metrisctl [flags] status [subAccountID]
+  metrisctl [flags] rescrape (-subaccount=<subAccountID> | -shoot=<shootName>) [-resend]
+
+Flags:
+`
+
+func main() {
+	flags := flag.NewFlagSet("metrisctl", flag.ExitOnError)
+	metrisURL := flags.String("url", "http://localhost:8080", "The URL where metris serves the admin endpoints")
+	token := flags.String("token", os.Getenv("ADMIN_TOKEN"), "The admin token of metris, defaults to the ADMIN_TOKEN env var")
+	timeout := flags.Duration("timeout", time.Minute, "The timeout for the requests to metris")
+	subAccountID := flags.String("subaccount", "", "The subAccountID to rescrape")
+	shootName := flags.String("shoot", "", "The shoot name to rescrape")
+	resend := flags.Bool("resend", false, "Resend the latest metric to EDP in addition to rescraping")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	// The flags may be set before and after the command
+	command := ""
+	args := make([]string, 0, len(os.Args))
+	for _, arg := range os.Args[1:] {
+		if command == "" && (arg == statusCmd || arg == rescrapeCmd) {
+			command = arg
+			continue
+		}
+		args = append(args, arg)
+	}
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("failed to parse flags: %v", err)
+	}
+
+	client := admin.Client{
+		HTTPClient: &http.Client{Timeout: *timeout},
+		URL:        *metrisURL,
+		Token:      *token,
+	}
+
+	switch command {
+	case statusCmd:
+		var body []byte
+		var err error
+		if flags.NArg() > 0 {
+			body, err = client.GetSubAccount(flags.Arg(0))
+		} else {
+			body, err = client.ListSubAccounts()
+		}
+		if err != nil {
+			log.Fatalf("failed to get status: %v", err)
+		}
+		fmt.Print(string(body))
+	case rescrapeCmd:
+		var result *admin.RescrapeResult
+		var err error
+		switch {
+		case *subAccountID != "" && *shootName == "":
+			result, err = client.RescrapeSubAccount(*subAccountID, *resend)
+		case *shootName != "" && *subAccountID == "":
+			result, err = client.RescrapeShoot(*shootName, *resend)
+		default:
+			log.Fatalf("either -subaccount or -shoot has to be set")
+		}
+		if err != nil {
+			log.Fatalf("failed to rescrape: %v", err)
+		}
+		resultBytes, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to json.Marshal result: %v", err)
+		}
+		fmt.Println(string(resultBytes))
+		if *resend && !result.Resent {
+			os.Exit(1)
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/kyma-incubator/metris/pkg/edp"
+)
+
+// schemagen writes the JSON Schemas of all the payloads metris sends, see go generate in pkg/edp.
+func main() {
+	dir := flag.String("dir", "schema", "The directory to write the JSON Schemas to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatalf("failed to create %s: %v", *dir, err)
+	}
+	for _, schema := range edp.Schemas {
+		jsonSchema, err := edp.JSONSchema(schema)
+		if err != nil {
+			log.Fatalf("failed to generate JSON Schema of %s: %v", schema.FileName(), err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(*dir, schema.FileName()), jsonSchema, 0644); err != nil {
+			log.Fatalf("failed to write %s: %v", schema.FileName(), err)
+		}
+	}
+}
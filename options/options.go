@@ -18,6 +18,15 @@ type Options struct {
 	GardenerNamespace   string
 	ScrapeInterval      time.Duration
 	WorkerPoolSize      int
+	SKRClientIdleTime   time.Duration
+	OutboxDir           string
+	OutboxMaxEvents     int
+	OutboxReplayTime    time.Duration
+	RuntimeGracePeriod  time.Duration
+	AggregationWindow   time.Duration
+	AggregationMaxGap   time.Duration
+	AggregationDir      string
+	LifecycleDir        string
 	DebugPort           int
 	ListenAddr          int
 	LogLevel            logrus.Level
@@ -28,6 +37,15 @@ func ParseArgs() *Options {
 	gardenerNamespace := flag.String("gardener-namespace", "garden-kyma-dev", "The namespace in gardener cluster where information about Kyma clusters are")
 	scrapeInterval := flag.Duration("scrape-interval", 3*time.Minute, "The wait duration of the interval between 2 executions of metrics generation")
 	workerPoolSize := flag.Int("worker-pool-size", 5, "The number of workers in the pool")
+	skrClientIdleTime := flag.Duration("skr-client-idle-time", 15*time.Minute, "The duration after which an unused client for a Kyma cluster is evicted")
+	outboxDir := flag.String("outbox-dir", "", "The directory where the events are kept till EDP accepted them, e.g. on a persistent volume. The outbox is disabled if empty")
+	outboxMaxEvents := flag.Int("outbox-max-events", 10000, "The maximum number of undelivered events in the outbox after which the oldest ones are dropped")
+	outboxReplayTime := flag.Duration("outbox-replay-time", time.Minute, "The wait duration between 2 attempts to deliver the events from the outbox")
+	runtimeGracePeriod := flag.Duration("runtime-grace-period", 30*time.Minute, "The duration a runtime may be missing from KEB before it is not tracked anymore")
+	aggregationWindow := flag.Duration("aggregation-window", time.Hour, "The window over which the consumption of a tenant is aggregated, e.g. 1h or 24h. The aggregation is disabled if 0")
+	aggregationMaxGap := flag.Duration("aggregation-max-gap", 10*time.Minute, "The maximum duration the values of a snapshot are held for when aggregating before the time counts as a gap")
+	aggregationDir := flag.String("aggregation-dir", "", "The directory where the open aggregation windows are kept across restarts, e.g. on a persistent volume")
+	lifecycleDir := flag.String("lifecycle-dir", "", "The directory where the lifecycle states of the runtimes are kept across restarts, e.g. on a persistent volume")
 	logLevelStr := flag.String("log-level", "info", "The log-level of the application. E.g. fatal, error, info, debug etc")
 	listenAddr := flag.Int("listen-addr", 8080, "The application starts server in this port to serve the metrics and healthz endpoints")
 	debugPort := flag.Int("debug-port", 0, "The custom port to debug when needed")
@@ -37,12 +55,27 @@ func ParseArgs() *Options {
 	if err != nil {
 		log.Fatalf("failed to parse log level: %v", logLevel)
 	}
+	if *skrClientIdleTime <= 0 {
+		log.Fatalf("--skr-client-idle-time must be positive: %v", *skrClientIdleTime)
+	}
+	if *outboxReplayTime <= 0 {
+		log.Fatalf("--outbox-replay-time must be positive: %v", *outboxReplayTime)
+	}
 
 	return &Options{
 		GardenerSecretPath: *gardenerSecretPath,
 		GardenerNamespace:  *gardenerNamespace,
 		ScrapeInterval:     *scrapeInterval,
 		WorkerPoolSize:     *workerPoolSize,
+		SKRClientIdleTime:  *skrClientIdleTime,
+		OutboxDir:          *outboxDir,
+		OutboxMaxEvents:    *outboxMaxEvents,
+		OutboxReplayTime:   *outboxReplayTime,
+		RuntimeGracePeriod: *runtimeGracePeriod,
+		AggregationWindow:  *aggregationWindow,
+		AggregationMaxGap:  *aggregationMaxGap,
+		AggregationDir:     *aggregationDir,
+		LifecycleDir:       *lifecycleDir,
 		DebugPort:          *debugPort,
 		LogLevel:           logLevel,
 		ListenAddr:         *listenAddr,
@@ -51,7 +84,10 @@ func ParseArgs() *Options {
 
 func (o *Options) String() string {
 	return fmt.Sprintf("--gardener-secret-path=%s --gardener-namespace=%s --scrape-interval=%v "+
-		"--worker-pool-size=%d --log-level=%s --listen-addr=%d, --debug-port=%d",
+		"--worker-pool-size=%d --skr-client-idle-time=%v --outbox-dir=%s --outbox-max-events=%d --outbox-replay-time=%v "+
+		"--runtime-grace-period=%v --aggregation-window=%v --aggregation-max-gap=%v --aggregation-dir=%s --lifecycle-dir=%s "+
+		"--log-level=%s --listen-addr=%d, --debug-port=%d",
 		o.GardenerSecretPath, o.GardenerNamespace, o.ScrapeInterval,
-		o.WorkerPoolSize, o.LogLevel, o.ListenAddr, o.DebugPort)
+		o.WorkerPoolSize, o.SKRClientIdleTime, o.OutboxDir, o.OutboxMaxEvents, o.OutboxReplayTime, o.RuntimeGracePeriod,
+		o.AggregationWindow, o.AggregationMaxGap, o.AggregationDir, o.LifecycleDir, o.LogLevel, o.ListenAddr, o.DebugPort)
 }
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Client talks to the admin endpoints of a running metris
+type Client struct {
+	HTTPClient *http.Client
+	URL        string
+	Token      string
+}
+
+// ListSubAccounts returns the scrape status of all the tracked runtimes
+func (c Client) ListSubAccounts() ([]byte, error) {
+	return c.do(http.MethodGet, subAccountsPath)
+}
+
+// GetSubAccount returns the scrape status of every runtime of a subaccount
+func (c Client) GetSubAccount(subAccountID string) ([]byte, error) {
+	return c.do(http.MethodGet, fmt.Sprintf("%s/%s", subAccountsPath, url.PathEscape(subAccountID)))
+}
+
+// RescrapeSubAccount triggers an immediate scrape of a subaccount and optionally resends its latest metric
+func (c Client) RescrapeSubAccount(subAccountID string, resend bool) (*RescrapeResult, error) {
+	path := strings.Replace(subAccountRescrapePath, fmt.Sprintf("{%s}", subAccountIDParam), url.PathEscape(subAccountID), 1)
+	return c.rescrape(path, resend)
+}
+
+// RescrapeShoot triggers an immediate scrape of the runtime owning a shoot and optionally resends its latest metric
+func (c Client) RescrapeShoot(shootName string, resend bool) (*RescrapeResult, error) {
+	path := strings.Replace(shootRescrapePath, fmt.Sprintf("{%s}", shootNameParam), url.PathEscape(shootName), 1)
+	return c.rescrape(path, resend)
+}
+
+func (c Client) rescrape(path string, resend bool) (*RescrapeResult, error) {
+	body, err := c.do(http.MethodPost, fmt.Sprintf("%s?%s=%t", path, resendParam, resend))
+	if err != nil {
+		return nil, err
+	}
+	result := new(RescrapeResult)
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal rescrape response")
+	}
+	return result, nil
+}
+
+func (c Client) do(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s%s", strings.TrimSuffix(c.URL, "/"), path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set(authorizationKeyHeader, fmt.Sprintf("%s%s", bearerPrefix, c.Token))
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read body")
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("metris returned HTTP: %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
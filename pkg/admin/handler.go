@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	subAccountsPath         = "/admin/subaccounts"
+	subAccountPath          = "/admin/subaccounts/{subAccountID}"
+	subAccountRescrapePath  = "/admin/subaccounts/{subAccountID}/rescrape"
+	shootRescrapePath       = "/admin/shoots/{shootName}/rescrape"
+	subAccountIDParam       = "subAccountID"
+	shootNameParam          = "shootName"
+	resendParam             = "resend"
+	bearerPrefix            = "Bearer "
+	authorizationKeyHeader  = "Authorization"
+	contentTypeKeyHeader    = "Content-Type"
+	contentTypeJSON         = "application/json"
+	subAccountNotTrackedMsg = "subaccount is not tracked"
+	shootNotTrackedMsg      = "shoot is not tracked"
+	adminActionsDisabledMsg = "admin actions are disabled as no admin token is configured"
+	unauthorizedAdminReqMsg = "missing or invalid admin token"
+)
+
+// StatusLister lists the scrape status of all the tracked runtimes
+type StatusLister interface {
+	ScrapeStatuses() []metriscache.ScrapeStatus
+}
+
+// Rescraper triggers scrapes and sends outside of the scrape interval
+type Rescraper interface {
+	// RuntimeForShoot returns the runtimeID and the subAccountID of the tracked runtime which owns the shoot
+	RuntimeForShoot(shootName string) (string, string, bool)
+	// Rescrape queues the runtimes of a subaccount to be scraped right away and returns false if it is not tracked
+	Rescrape(subAccountID string) bool
+	// RescrapeRuntime queues a runtime to be scraped right away and returns false if it is not tracked
+	RescrapeRuntime(runtimeID string) bool
+	// Resend sends the latest metrics of a subaccount to EDP again and returns the HTTP status code of EDP
+	Resend(subAccountID string) (int, error)
+	// ResendRuntime sends the latest metric of a runtime to EDP again and returns the HTTP status code of EDP
+	ResendRuntime(runtimeID string) (int, error)
+}
+
+// RescrapeResult is the response of a rescrape request
+type RescrapeResult struct {
+	SubAccountID  string `json:"subaccount_id"`
+	RuntimeID     string `json:"runtime_id,omitempty"`
+	Queued        bool   `json:"queued"`
+	Resent        bool   `json:"resent"`
+	EDPStatusCode int    `json:"edp_status_code,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Handler serves the admin endpoints which help to troubleshoot the metering of the subaccounts.
+// The endpoints which trigger actions require the Token as bearer token and are disabled without one.
+type Handler struct {
+	Statuses  StatusLister
+	Rescraper Rescraper
+	Token     string
+	Logger    *logrus.Logger
+}
+
+// Register adds the admin endpoints to the router
+func (h Handler) Register(router *mux.Router) {
+	router.Path(subAccountsPath).Methods(http.MethodGet).HandlerFunc(h.listSubAccounts)
+	router.Path(subAccountPath).Methods(http.MethodGet).HandlerFunc(h.getSubAccount)
+	router.Path(subAccountRescrapePath).Methods(http.MethodPost).HandlerFunc(h.authenticated(h.rescrapeSubAccount))
+	router.Path(shootRescrapePath).Methods(http.MethodPost).HandlerFunc(h.authenticated(h.rescrapeShoot))
+}
+
+func (h Handler) listSubAccounts(writer http.ResponseWriter, _ *http.Request) {
+	h.writeJSON(writer, http.StatusOK, h.Statuses.ScrapeStatuses())
+}
+
+// getSubAccount returns the status of every runtime of a subaccount
+func (h Handler) getSubAccount(writer http.ResponseWriter, request *http.Request) {
+	subAccountID := mux.Vars(request)[subAccountIDParam]
+	var statuses []metriscache.ScrapeStatus
+	for _, status := range h.Statuses.ScrapeStatuses() {
+		if status.SubAccountID == subAccountID {
+			statuses = append(statuses, status)
+		}
+	}
+	if len(statuses) == 0 {
+		http.Error(writer, subAccountNotTrackedMsg, http.StatusNotFound)
+		return
+	}
+	h.writeJSON(writer, http.StatusOK, statuses)
+}
+
+func (h Handler) rescrapeSubAccount(writer http.ResponseWriter, request *http.Request) {
+	subAccountID := mux.Vars(request)[subAccountIDParam]
+	result := RescrapeResult{SubAccountID: subAccountID}
+	h.rescrape(writer, request, result, func() bool {
+		return h.Rescraper.Rescrape(subAccountID)
+	}, func() (int, error) {
+		return h.Rescraper.Resend(subAccountID)
+	})
+}
+
+// rescrapeShoot only rescrapes the runtime which owns the shoot, not the other runtimes of its subaccount
+func (h Handler) rescrapeShoot(writer http.ResponseWriter, request *http.Request) {
+	runtimeID, subAccountID, found := h.Rescraper.RuntimeForShoot(mux.Vars(request)[shootNameParam])
+	if !found {
+		http.Error(writer, shootNotTrackedMsg, http.StatusNotFound)
+		return
+	}
+	result := RescrapeResult{SubAccountID: subAccountID, RuntimeID: runtimeID}
+	h.rescrape(writer, request, result, func() bool {
+		return h.Rescraper.RescrapeRuntime(runtimeID)
+	}, func() (int, error) {
+		return h.Rescraper.ResendRuntime(runtimeID)
+	})
+}
+
+// rescrape queues the runtimes to be scraped right away and resends their latest metric when resend=true is set
+func (h Handler) rescrape(writer http.ResponseWriter, request *http.Request, result RescrapeResult, queue func() bool, resendLatest func() (int, error)) {
+	resend := false
+	if resendStr := request.URL.Query().Get(resendParam); resendStr != "" {
+		var err error
+		if resend, err = strconv.ParseBool(resendStr); err != nil {
+			http.Error(writer, "bad value for resend", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if result.Queued = queue(); !result.Queued {
+		notTrackedMsg := subAccountNotTrackedMsg
+		if result.RuntimeID != "" {
+			notTrackedMsg = shootNotTrackedMsg
+		}
+		http.Error(writer, notTrackedMsg, http.StatusNotFound)
+		return
+	}
+	if resend {
+		statusCode, err := resendLatest()
+		result.EDPStatusCode = statusCode
+		result.Resent = err == nil
+		if err != nil {
+			h.Logger.Errorf("failed to resend metric for subAccountID: %s, runtimeID: %s: %v", result.SubAccountID, result.RuntimeID, err)
+			result.Error = err.Error()
+		}
+	}
+
+	h.Logger.Infof("rescrape requested for subAccountID: %s, runtimeID: %s with resend: %v", result.SubAccountID, result.RuntimeID, resend)
+
+	// The rescrape is queued even if the resend failed, the result tells about the resend
+	h.writeJSON(writer, http.StatusAccepted, result)
+}
+
+// authenticated only lets the requests through which carry the admin token
+func (h Handler) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if h.Token == "" {
+			http.Error(writer, adminActionsDisabledMsg, http.StatusForbidden)
+			return
+		}
+		authHeader := request.Header.Get(authorizationKeyHeader)
+		token := strings.TrimPrefix(authHeader, bearerPrefix)
+		if !strings.HasPrefix(authHeader, bearerPrefix) || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+			http.Error(writer, unauthorizedAdminReqMsg, http.StatusUnauthorized)
+			return
+		}
+		next(writer, request)
+	}
+}
+
+func (h Handler) writeJSON(writer http.ResponseWriter, statusCode int, body interface{}) {
+	writer.Header().Set(contentTypeKeyHeader, contentTypeJSON)
+	writer.WriteHeader(statusCode)
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		h.Logger.Errorf("failed to write admin response: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+const testToken = "admin-token"
+
+type fakeStatusLister []metriscache.ScrapeStatus
+
+func (f fakeStatusLister) ScrapeStatuses() []metriscache.ScrapeStatus {
+	return f
+}
+
+// fakeRescraper tracks a runtime with the ID runtime-<subAccountID> for every subaccount
+type fakeRescraper struct {
+	shoots            map[string]string
+	metrics           map[string]bool
+	rescraped         []string
+	rescrapedRuntimes []string
+	resentSubs        []string
+	resentRuntimes    []string
+}
+
+func (f *fakeRescraper) RuntimeForShoot(shootName string) (string, string, bool) {
+	for subAccountID, shoot := range f.shoots {
+		if shoot == shootName {
+			return "runtime-" + subAccountID, subAccountID, true
+		}
+	}
+	return "", "", false
+}
+
+func (f *fakeRescraper) RescrapeRuntime(runtimeID string) bool {
+	if _, found := f.shoots[strings.TrimPrefix(runtimeID, "runtime-")]; !found {
+		return false
+	}
+	f.rescrapedRuntimes = append(f.rescrapedRuntimes, runtimeID)
+	return true
+}
+
+func (f *fakeRescraper) ResendRuntime(runtimeID string) (int, error) {
+	if !f.metrics[strings.TrimPrefix(runtimeID, "runtime-")] {
+		return 0, fmt.Errorf("old metrics for runtimeID: %s not found", runtimeID)
+	}
+	f.resentRuntimes = append(f.resentRuntimes, runtimeID)
+	return http.StatusCreated, nil
+}
+
+func (f *fakeRescraper) Rescrape(subAccountID string) bool {
+	if _, found := f.shoots[subAccountID]; !found {
+		return false
+	}
+	f.rescraped = append(f.rescraped, subAccountID)
+	return true
+}
+
+func (f *fakeRescraper) Resend(subAccountID string) (int, error) {
+	if !f.metrics[subAccountID] {
+		return 0, fmt.Errorf("old metrics for subAccountID: %s not found", subAccountID)
+	}
+	f.resentSubs = append(f.resentSubs, subAccountID)
+	return http.StatusCreated, nil
+}
+
+func TestSubAccounts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	lastScrape := time.Date(2021, 2, 1, 10, 0, 0, 0, time.UTC)
+	statuses := fakeStatusLister{
+		{
+			SubAccountID:         "foo",
+			ShootName:            "shoot-foo",
+			LastSuccessfulScrape: &lastScrape,
+			LastEDPStatusCode:    http.StatusCreated,
+		},
+		{
+			SubAccountID:     "bar",
+			RuntimeID:        "runtime-bar-1",
+			ShootName:        "shoot-bar",
+			LastError:        "kubeconfig for shoot: shoot-bar not found",
+			LastErrorStage:   "kubeconfig",
+			LastPayloadStale: true,
+		},
+		{
+			SubAccountID: "bar",
+			RuntimeID:    "runtime-bar-2",
+			ShootName:    "shoot-bar-2",
+		},
+	}
+	router := mux.NewRouter()
+	Handler{Statuses: statuses, Logger: logrus.New()}.Register(router)
+
+	t.Run("list all subaccounts", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/subaccounts", nil))
+		g.Expect(recorder.Code).To(gomega.Equal(http.StatusOK))
+		g.Expect(recorder.Header().Get("Content-Type")).To(gomega.Equal("application/json"))
+
+		var gotStatuses []metriscache.ScrapeStatus
+		err := json.Unmarshal(recorder.Body.Bytes(), &gotStatuses)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(gotStatuses).To(gomega.HaveLen(3))
+		g.Expect(gotStatuses[0].SubAccountID).To(gomega.Equal("foo"))
+		g.Expect(gotStatuses[0].LastSuccessfulScrape.Equal(lastScrape)).To(gomega.BeTrue())
+		g.Expect(gotStatuses[1]).To(gomega.Equal(statuses[1]))
+	})
+
+	t.Run("get the runtimes of a subaccount", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/subaccounts/bar", nil))
+		g.Expect(recorder.Code).To(gomega.Equal(http.StatusOK))
+
+		var gotStatuses []metriscache.ScrapeStatus
+		err := json.Unmarshal(recorder.Body.Bytes(), &gotStatuses)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(gotStatuses).To(gomega.Equal([]metriscache.ScrapeStatus{statuses[1], statuses[2]}))
+	})
+
+	t.Run("get a subaccount which is not tracked", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/subaccounts/doesnotexist", nil))
+		g.Expect(recorder.Code).To(gomega.Equal(http.StatusNotFound))
+	})
+}
+
+func TestRescrape(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	rescraper := &fakeRescraper{
+		shoots:  map[string]string{"foo": "shoot-foo", "bar": "shoot-bar"},
+		metrics: map[string]bool{"foo": true},
+	}
+	router := mux.NewRouter()
+	Handler{Statuses: fakeStatusLister{}, Rescraper: rescraper, Token: testToken, Logger: logrus.New()}.Register(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	client := Client{HTTPClient: http.DefaultClient, URL: srv.URL, Token: testToken}
+
+	t.Run("rescrape and resend a subaccount", func(t *testing.T) {
+		result, err := client.RescrapeSubAccount("foo", true)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(*result).To(gomega.Equal(RescrapeResult{
+			SubAccountID:  "foo",
+			Queued:        true,
+			Resent:        true,
+			EDPStatusCode: http.StatusCreated,
+		}))
+		g.Expect(rescraper.resentSubs).To(gomega.Equal([]string{"foo"}))
+	})
+
+	t.Run("rescrape a shoot which has no metric to resend yet", func(t *testing.T) {
+		result, err := client.RescrapeShoot("shoot-bar", true)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(result.SubAccountID).To(gomega.Equal("bar"))
+		g.Expect(result.RuntimeID).To(gomega.Equal("runtime-bar"))
+		g.Expect(result.Queued).To(gomega.BeTrue())
+		g.Expect(result.Resent).To(gomega.BeFalse())
+		g.Expect(result.Error).To(gomega.Equal("old metrics for runtimeID: runtime-bar not found"))
+		// Only the runtime of the shoot is rescraped, not the whole subaccount
+		g.Expect(rescraper.rescraped).To(gomega.Equal([]string{"foo"}))
+		g.Expect(rescraper.rescrapedRuntimes).To(gomega.Equal([]string{"runtime-bar"}))
+	})
+
+	t.Run("rescrape and resend a shoot", func(t *testing.T) {
+		result, err := client.RescrapeShoot("shoot-foo", true)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(*result).To(gomega.Equal(RescrapeResult{
+			SubAccountID:  "foo",
+			RuntimeID:     "runtime-foo",
+			Queued:        true,
+			Resent:        true,
+			EDPStatusCode: http.StatusCreated,
+		}))
+		g.Expect(rescraper.resentSubs).To(gomega.Equal([]string{"foo"}))
+		g.Expect(rescraper.resentRuntimes).To(gomega.Equal([]string{"runtime-foo"}))
+	})
+
+	t.Run("rescrape a subaccount or shoot which is not tracked", func(t *testing.T) {
+		_, err := client.RescrapeSubAccount("doesnotexist", false)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		g.Expect(err.Error()).To(gomega.Equal("metris returned HTTP: 404: subaccount is not tracked"))
+
+		_, err = client.RescrapeShoot("doesnotexist", false)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		g.Expect(err.Error()).To(gomega.Equal("metris returned HTTP: 404: shoot is not tracked"))
+	})
+
+	t.Run("rescrape with a bad token", func(t *testing.T) {
+		badClient := Client{HTTPClient: http.DefaultClient, URL: srv.URL, Token: "bad-token"}
+		_, err := badClient.RescrapeSubAccount("foo", false)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		g.Expect(err.Error()).To(gomega.Equal("metris returned HTTP: 401: missing or invalid admin token"))
+
+		noTokenClient := Client{HTTPClient: http.DefaultClient, URL: srv.URL}
+		_, err = noTokenClient.RescrapeSubAccount("foo", false)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		g.Expect(err.Error()).To(gomega.Equal("metris returned HTTP: 401: missing or invalid admin token"))
+	})
+
+	t.Run("rescrape without an admin token configured", func(t *testing.T) {
+		disabledRouter := mux.NewRouter()
+		Handler{Statuses: fakeStatusLister{}, Rescraper: rescraper, Logger: logrus.New()}.Register(disabledRouter)
+		recorder := httptest.NewRecorder()
+		disabledRouter.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/subaccounts/foo/rescrape", nil))
+		g.Expect(recorder.Code).To(gomega.Equal(http.StatusForbidden))
+	})
+}
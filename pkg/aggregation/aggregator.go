@@ -0,0 +1,334 @@
+package aggregation
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/edp"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	stateFileName   = "aggregation.json"
+	tmpFileSuffix   = ".tmp"
+	stateFileMode   = 0600
+	dirMode         = 0700
+	hoursPrecision  = 1e6
+	defaultInterval = time.Minute
+)
+
+// Summary is a closed window of a tenant
+type Summary struct {
+	Tenant string
+	Usage  edp.UsageWindow
+}
+
+// usage is the consumption which was integrated in a window so far
+type usage struct {
+	CPUHours       float64 `json:"cpu_hours"`
+	RAMGbHours     float64 `json:"ram_gb_hours"`
+	StorageGbHours float64 `json:"storage_gb_hours"`
+	IPHours        float64 `json:"ip_hours"`
+	RuntimeHours   float64 `json:"runtime_hours"`
+	GapHours       float64 `json:"gap_hours"`
+}
+
+// runtimeState is the latest snapshot of a runtime. Its values are held till the next snapshot.
+type runtimeState struct {
+	Tenant    string    `json:"tenant"`
+	SampledAt time.Time `json:"sampled_at"`
+	// AccountedUntil is the time till which the snapshot was integrated into the windows
+	AccountedUntil time.Time `json:"accounted_until"`
+	CPUs           float64   `json:"cpus"`
+	RAMGb          float64   `json:"ram_gb"`
+	StorageGb      float64   `json:"storage_gb"`
+	IPs            float64   `json:"ips"`
+}
+
+// state is everything which is needed to continue the aggregation after a restart
+type state struct {
+	// Windows maps the tenants to their open windows by the start of the window in Unix seconds
+	Windows  map[string]map[int64]*usage `json:"windows"`
+	Runtimes map[string]*runtimeState    `json:"runtimes"`
+	// FlushedUntil is the end of the latest closed window
+	FlushedUntil time.Time `json:"flushed_until"`
+}
+
+// Aggregator integrates the successive snapshots of the runtimes over fixed windows which are aligned to UTC,
+// e.g. to full hours or days. The values of a snapshot are held till the next one, but at most for MaxGap.
+// The time in which a runtime had no snapshot for longer than MaxGap is reported as a gap instead of being guessed.
+// The state is kept in Dir if set, so that the open windows survive a restart.
+// All the methods are no-ops on a nil aggregator.
+type Aggregator struct {
+	Window        time.Duration
+	MaxGap        time.Duration
+	FlushInterval time.Duration
+	Dir           string
+	Logger        *logrus.Logger
+
+	mu    sync.Mutex
+	state state
+	// startedAt is when the aggregation started without a previous state, windows which started before are partial
+	startedAt time.Time
+}
+
+// New creates an aggregator and loads the state which was left by a previous run from dir
+func New(window, maxGap time.Duration, dir string, logger *logrus.Logger) (*Aggregator, error) {
+	a := &Aggregator{
+		Window:        window,
+		MaxGap:        maxGap,
+		FlushInterval: defaultInterval,
+		Dir:           dir,
+		Logger:        logger,
+		state: state{
+			Windows:  make(map[string]map[int64]*usage),
+			Runtimes: make(map[string]*runtimeState),
+		},
+		startedAt: time.Now(),
+	}
+	if dir == "" {
+		return a, nil
+	}
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, errors.Wrapf(err, "failed to create aggregation dir")
+	}
+	stateBytes, err := ioutil.ReadFile(a.path())
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read aggregation state")
+	}
+	loaded := state{}
+	if err := json.Unmarshal(stateBytes, &loaded); err != nil {
+		logger.Warnf("discarding unreadable aggregation state: %v", err)
+		return a, nil
+	}
+	if loaded.Windows != nil {
+		a.state.Windows = loaded.Windows
+	}
+	if loaded.Runtimes != nil {
+		a.state.Runtimes = loaded.Runtimes
+	}
+	a.state.FlushedUntil = loaded.FlushedUntil
+	// The windows were covered before the restart
+	a.startedAt = time.Time{}
+	logger.Infof("loaded aggregation state of %d runtimes from: %s", len(a.state.Runtimes), dir)
+	return a, nil
+}
+
+// Observe integrates the previous snapshot of the runtime till this one
+func (a *Aggregator) Observe(tenant, runtimeID string, metric *edp.ConsumptionMetrics, at time.Time) {
+	if a == nil || metric == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if previous, isFound := a.state.Runtimes[runtimeID]; isFound {
+		a.advance(previous, at, true)
+	}
+	a.state.Runtimes[runtimeID] = &runtimeState{
+		Tenant:         tenant,
+		SampledAt:      at,
+		AccountedUntil: at,
+		CPUs:           float64(metric.Compute.ProvisionedCpus),
+		RAMGb:          metric.Compute.ProvisionedRAMGb,
+		StorageGb:      float64(metric.Compute.ProvisionedVolumes.SizeGbTotal),
+		IPs:            float64(metric.Networking.ProvisionedIPs),
+	}
+}
+
+// Forget integrates the last snapshot of a runtime which is not tracked anymore and drops it
+func (a *Aggregator) Forget(runtimeID string, at time.Time) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if previous, isFound := a.state.Runtimes[runtimeID]; isFound {
+		a.advance(previous, at, false)
+		delete(a.state.Runtimes, runtimeID)
+	}
+}
+
+// Flush closes the windows which cannot get any more snapshots, i.e. which ended at least MaxGap ago
+func (a *Aggregator) Flush(now time.Time) []Summary {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	closeUntil := now.Add(-a.MaxGap).Truncate(a.Window)
+	if !closeUntil.After(a.state.FlushedUntil) {
+		return nil
+	}
+	for runtimeID, runtime := range a.state.Runtimes {
+		a.advance(runtime, closeUntil, true)
+		if runtime.SampledAt.Add(a.MaxGap + a.Window).Before(closeUntil) {
+			// The runtime did not have any snapshot for a whole window hence it is gone
+			delete(a.state.Runtimes, runtimeID)
+		}
+	}
+
+	var summaries []Summary
+	for tenant, windows := range a.state.Windows {
+		for start, windowUsage := range windows {
+			windowStart := time.Unix(start, 0).UTC()
+			windowEnd := windowStart.Add(a.Window)
+			if windowEnd.After(closeUntil) {
+				continue
+			}
+			summaries = append(summaries, Summary{
+				Tenant: tenant,
+				Usage: edp.UsageWindow{
+					EventID:        edp.NewEventID(tenant, windowStart.Format(time.RFC3339), windowEnd.Format(time.RFC3339)),
+					WindowStart:    windowStart.Format(time.RFC3339),
+					WindowEnd:      windowEnd.Format(time.RFC3339),
+					CPUHours:       roundHours(windowUsage.CPUHours),
+					RAMGbHours:     roundHours(windowUsage.RAMGbHours),
+					StorageGbHours: roundHours(windowUsage.StorageGbHours),
+					IPHours:        roundHours(windowUsage.IPHours),
+					RuntimeHours:   roundHours(windowUsage.RuntimeHours),
+					GapHours:       roundHours(windowUsage.GapHours),
+					Partial:        windowUsage.GapHours > 0 || windowStart.Before(a.startedAt),
+				},
+			})
+			delete(windows, start)
+		}
+		if len(windows) == 0 {
+			delete(a.state.Windows, tenant)
+		}
+	}
+	a.state.FlushedUntil = closeUntil
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Usage.WindowStart != summaries[j].Usage.WindowStart {
+			return summaries[i].Usage.WindowStart < summaries[j].Usage.WindowStart
+		}
+		return summaries[i].Tenant < summaries[j].Tenant
+	})
+	return summaries
+}
+
+// Run flushes the closed windows every FlushInterval until the context is done and persists the state afterwards
+func (a *Aggregator) Run(ctx context.Context, send func(summary Summary)) {
+	if a == nil {
+		return
+	}
+	ticker := time.NewTicker(a.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, summary := range a.Flush(time.Now()) {
+				send(summary)
+			}
+			if err := a.Save(); err != nil {
+				a.Logger.Errorf("failed to save aggregation state: %v", err)
+			}
+		}
+	}
+}
+
+// Save persists the state in Dir
+func (a *Aggregator) Save() error {
+	if a == nil || a.Dir == "" {
+		return nil
+	}
+	a.mu.Lock()
+	stateBytes, err := json.Marshal(a.state)
+	a.mu.Unlock()
+	if err != nil {
+		return errors.Wrapf(err, "failed to json.Marshal aggregation state")
+	}
+	tmpPath := a.path() + tmpFileSuffix
+	if err := ioutil.WriteFile(tmpPath, stateBytes, stateFileMode); err != nil {
+		return errors.Wrapf(err, "failed to write aggregation state")
+	}
+	if err := os.Rename(tmpPath, a.path()); err != nil {
+		return errors.Wrapf(err, "failed to write aggregation state")
+	}
+	return nil
+}
+
+// advance integrates the snapshot of a runtime till the given time. The time after MaxGap is a gap
+// if the runtime is known to have existed, i.e. if it got another snapshot or is still tracked.
+func (a *Aggregator) advance(runtime *runtimeState, until time.Time, hasExisted bool) {
+	from := latest(runtime.AccountedUntil, a.state.FlushedUntil)
+	if !until.After(from) {
+		return
+	}
+	coveredUntil := runtime.SampledAt.Add(a.MaxGap)
+	if coveredUntil.After(from) {
+		end := earliest(until, coveredUntil)
+		a.forEachWindow(runtime.Tenant, from, end, func(windowUsage *usage, hours float64) {
+			windowUsage.CPUHours += runtime.CPUs * hours
+			windowUsage.RAMGbHours += runtime.RAMGb * hours
+			windowUsage.StorageGbHours += runtime.StorageGb * hours
+			windowUsage.IPHours += runtime.IPs * hours
+			windowUsage.RuntimeHours += hours
+		})
+		from = end
+	}
+	if hasExisted && until.After(from) {
+		a.forEachWindow(runtime.Tenant, from, until, func(windowUsage *usage, hours float64) {
+			windowUsage.GapHours += hours
+		})
+	}
+	runtime.AccountedUntil = until
+}
+
+// forEachWindow splits the time between from and to into the windows of the tenant
+func (a *Aggregator) forEachWindow(tenant string, from, to time.Time, add func(windowUsage *usage, hours float64)) {
+	windows, isFound := a.state.Windows[tenant]
+	if !isFound {
+		windows = make(map[int64]*usage)
+		a.state.Windows[tenant] = windows
+	}
+	for from.Before(to) {
+		windowStart := from.Truncate(a.Window)
+		end := earliest(windowStart.Add(a.Window), to)
+		windowUsage, isFound := windows[windowStart.Unix()]
+		if !isFound {
+			windowUsage = new(usage)
+			windows[windowStart.Unix()] = windowUsage
+		}
+		add(windowUsage, end.Sub(from).Hours())
+		from = end
+	}
+}
+
+func (a *Aggregator) path() string {
+	return filepath.Join(a.Dir, stateFileName)
+}
+
+func roundHours(hours float64) float64 {
+	return math.Round(hours*hoursPrecision) / hoursPrecision
+}
+
+func earliest(t1, t2 time.Time) time.Time {
+	if t1.Before(t2) {
+		return t1
+	}
+	return t2
+}
+
+func latest(t1, t2 time.Time) time.Time {
+	if t1.After(t2) {
+		return t1
+	}
+	return t2
+}
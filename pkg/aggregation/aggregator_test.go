@@ -0,0 +1,163 @@
+package aggregation
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/edp"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testTenant    = "tenant"
+	testRuntimeID = "runtime"
+)
+
+var base = time.Date(2021, 2, 1, 10, 0, 0, 0, time.UTC)
+
+func newMetric(cpus int, ramGb float64, storageGb int64, ips int) *edp.ConsumptionMetrics {
+	return &edp.ConsumptionMetrics{
+		Compute: edp.Compute{
+			ProvisionedCpus:    cpus,
+			ProvisionedRAMGb:   ramGb,
+			ProvisionedVolumes: edp.ProvisionedVolumes{SizeGbTotal: storageGb},
+		},
+		Networking: edp.Networking{ProvisionedIPs: ips},
+	}
+}
+
+func newTestAggregator(g *gomega.WithT, dir string) *Aggregator {
+	a, err := New(time.Hour, 10*time.Minute, dir, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	a.startedAt = base
+	return a
+}
+
+// observeEvery observes the same metric every interval from the start till the end
+func observeEvery(a *Aggregator, metric *edp.ConsumptionMetrics, start, end time.Time, interval time.Duration) {
+	for at := start; !at.After(end); at = at.Add(interval) {
+		a.Observe(testTenant, testRuntimeID, metric, at)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t.Run("steady snapshots", func(t *testing.T) {
+		a := newTestAggregator(g, "")
+		observeEvery(a, newMetric(4, 16, 100, 2), base, base.Add(70*time.Minute), 3*time.Minute)
+
+		// The window is closed once no snapshot can be added anymore
+		g.Expect(a.Flush(base.Add(65 * time.Minute))).To(gomega.BeEmpty())
+		summaries := a.Flush(base.Add(75 * time.Minute))
+		g.Expect(summaries).To(gomega.Equal([]Summary{{
+			Tenant: testTenant,
+			Usage: edp.UsageWindow{
+				EventID:        edp.NewEventID(testTenant, "2021-02-01T10:00:00Z", "2021-02-01T11:00:00Z"),
+				WindowStart:    "2021-02-01T10:00:00Z",
+				WindowEnd:      "2021-02-01T11:00:00Z",
+				CPUHours:       4,
+				RAMGbHours:     16,
+				StorageGbHours: 100,
+				IPHours:        2,
+				RuntimeHours:   1,
+			},
+		}}))
+		g.Expect(a.Flush(base.Add(75 * time.Minute))).To(gomega.BeEmpty())
+	})
+
+	t.Run("changing snapshots are weighted by time", func(t *testing.T) {
+		a := newTestAggregator(g, "")
+		observeEvery(a, newMetric(2, 8, 0, 0), base, base.Add(30*time.Minute), 5*time.Minute)
+		observeEvery(a, newMetric(4, 16, 0, 0), base.Add(35*time.Minute), base.Add(70*time.Minute), 5*time.Minute)
+
+		summaries := a.Flush(base.Add(75 * time.Minute))
+		g.Expect(summaries).To(gomega.HaveLen(1))
+		// 2 CPUs for 35m and 4 CPUs for 25m
+		g.Expect(summaries[0].Usage.CPUHours).To(gomega.BeNumerically("~", 2*35.0/60+4*25.0/60, 1e-6))
+		g.Expect(summaries[0].Usage.RAMGbHours).To(gomega.BeNumerically("~", 8*35.0/60+16*25.0/60, 1e-6))
+	})
+
+	t.Run("gap between snapshots", func(t *testing.T) {
+		a := newTestAggregator(g, "")
+		a.Observe(testTenant, testRuntimeID, newMetric(4, 16, 0, 0), base)
+		observeEvery(a, newMetric(4, 16, 0, 0), base.Add(40*time.Minute), base.Add(70*time.Minute), 5*time.Minute)
+
+		summaries := a.Flush(base.Add(75 * time.Minute))
+		g.Expect(summaries).To(gomega.HaveLen(1))
+		// The first snapshot is held for the max gap only
+		g.Expect(summaries[0].Usage.RuntimeHours).To(gomega.BeNumerically("~", 0.5, 1e-6))
+		g.Expect(summaries[0].Usage.GapHours).To(gomega.BeNumerically("~", 0.5, 1e-6))
+		g.Expect(summaries[0].Usage.CPUHours).To(gomega.BeNumerically("~", 2, 1e-6))
+		g.Expect(summaries[0].Usage.Partial).To(gomega.BeTrue())
+	})
+
+	t.Run("snapshots across windows", func(t *testing.T) {
+		a := newTestAggregator(g, "")
+		observeEvery(a, newMetric(1, 0, 0, 0), base.Add(30*time.Minute), base.Add(150*time.Minute), 5*time.Minute)
+
+		summaries := a.Flush(base.Add(130 * time.Minute))
+		g.Expect(summaries).To(gomega.HaveLen(2))
+		g.Expect(summaries[0].Usage.WindowStart).To(gomega.Equal("2021-02-01T10:00:00Z"))
+		g.Expect(summaries[0].Usage.CPUHours).To(gomega.BeNumerically("~", 0.5, 1e-6))
+		g.Expect(summaries[1].Usage.WindowStart).To(gomega.Equal("2021-02-01T11:00:00Z"))
+		g.Expect(summaries[1].Usage.CPUHours).To(gomega.BeNumerically("~", 1, 1e-6))
+		g.Expect(summaries[1].Usage.Partial).To(gomega.BeFalse())
+	})
+
+	t.Run("window which started before the aggregation", func(t *testing.T) {
+		a := newTestAggregator(g, "")
+		a.startedAt = base.Add(20 * time.Minute)
+		observeEvery(a, newMetric(1, 0, 0, 0), base.Add(20*time.Minute), base.Add(70*time.Minute), 5*time.Minute)
+
+		summaries := a.Flush(base.Add(75 * time.Minute))
+		g.Expect(summaries).To(gomega.HaveLen(1))
+		g.Expect(summaries[0].Usage.Partial).To(gomega.BeTrue())
+		g.Expect(summaries[0].Usage.GapHours).To(gomega.BeZero())
+	})
+
+	t.Run("forgotten runtime", func(t *testing.T) {
+		a := newTestAggregator(g, "")
+		observeEvery(a, newMetric(4, 0, 0, 0), base, base.Add(25*time.Minute), 5*time.Minute)
+		a.Forget(testRuntimeID, base.Add(30*time.Minute))
+
+		summaries := a.Flush(base.Add(75 * time.Minute))
+		g.Expect(summaries).To(gomega.HaveLen(1))
+		g.Expect(summaries[0].Usage.CPUHours).To(gomega.BeNumerically("~", 2, 1e-6))
+		g.Expect(summaries[0].Usage.GapHours).To(gomega.BeZero())
+		g.Expect(a.state.Runtimes).To(gomega.BeEmpty())
+	})
+}
+
+func TestRestart(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "aggregation")
+	g.Expect(err).Should(gomega.BeNil())
+	defer os.RemoveAll(dir)
+
+	a := newTestAggregator(g, dir)
+	observeEvery(a, newMetric(4, 0, 0, 0), base, base.Add(30*time.Minute), 5*time.Minute)
+	g.Expect(a.Save()).Should(gomega.BeNil())
+
+	// The open window is continued after the restart
+	restarted, err := New(time.Hour, 10*time.Minute, dir, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	observeEvery(restarted, newMetric(4, 0, 0, 0), base.Add(35*time.Minute), base.Add(70*time.Minute), 5*time.Minute)
+
+	summaries := restarted.Flush(base.Add(75 * time.Minute))
+	g.Expect(summaries).To(gomega.HaveLen(1))
+	g.Expect(summaries[0].Usage.CPUHours).To(gomega.BeNumerically("~", 4, 1e-6))
+	g.Expect(summaries[0].Usage.Partial).To(gomega.BeFalse())
+}
+
+func TestNilAggregator(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var a *Aggregator
+	a.Observe(testTenant, testRuntimeID, newMetric(1, 0, 0, 0), base)
+	a.Forget(testRuntimeID, base)
+	g.Expect(a.Flush(base)).To(gomega.BeNil())
+	g.Expect(a.Save()).Should(gomega.BeNil())
+}
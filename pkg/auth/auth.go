@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/filewatch"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// MethodNone sends the requests without credentials
+	MethodNone = "none"
+	// MethodToken sends a static bearer token
+	MethodToken = "token"
+	// MethodOAuth2 fetches bearer tokens with the OAuth2 client-credentials flow
+	MethodOAuth2 = "oauth2"
+
+	authorizationKeyHeader = "Authorization"
+)
+
+// Config contains how the requests to an upstream are authenticated.
+// A client certificate for mTLS can be combined with any method.
+type Config struct {
+	Method             string   `envconfig:"METHOD"`
+	Token              string   `envconfig:"TOKEN"`
+	TokenFile          string   `envconfig:"TOKEN_FILE"`
+	OAuth2TokenURL     string   `envconfig:"OAUTH2_TOKEN_URL"`
+	OAuth2ClientID     string   `envconfig:"OAUTH2_CLIENT_ID"`
+	OAuth2ClientSecret string   `envconfig:"OAUTH2_CLIENT_SECRET"`
+	OAuth2Scopes       []string `envconfig:"OAUTH2_SCOPES"`
+	TLSCertFile        string   `envconfig:"TLS_CERT_FILE"`
+	TLSKeyFile         string   `envconfig:"TLS_KEY_FILE"`
+	TLSCAFile          string   `envconfig:"TLS_CA_FILE"`
+}
+
+// AuthMethod returns the configured method or guesses it from the credentials which are set
+func (c Config) AuthMethod() string {
+	switch {
+	case c.Method != "":
+		return c.Method
+	case c.OAuth2TokenURL != "":
+		return MethodOAuth2
+	case c.Token != "" || c.TokenFile != "":
+		return MethodToken
+	default:
+		return MethodNone
+	}
+}
+
+// NewHTTPClient returns an HTTP client which authenticates every request as configured
+func NewHTTPClient(config Config, timeout time.Duration) (*http.Client, error) {
+	baseTransport, err := newBaseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper
+	switch method := config.AuthMethod(); method {
+	case MethodNone:
+		transport = baseTransport
+	case MethodToken:
+		tokenTransport, err := newTokenTransport(config, baseTransport)
+		if err != nil {
+			return nil, err
+		}
+		transport = tokenTransport
+	case MethodOAuth2:
+		if config.OAuth2TokenURL == "" || config.OAuth2ClientID == "" || config.OAuth2ClientSecret == "" {
+			return nil, fmt.Errorf("a token URL, client ID and client secret are required for auth method: %s", method)
+		}
+		clientCredentials := clientcredentials.Config{
+			ClientID:     config.OAuth2ClientID,
+			ClientSecret: config.OAuth2ClientSecret,
+			TokenURL:     config.OAuth2TokenURL,
+			Scopes:       config.OAuth2Scopes,
+		}
+		// The token endpoint is called with the same client certificate
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+			Transport: baseTransport,
+			Timeout:   timeout,
+		})
+		// The token is cached and fetched again shortly before it expires
+		transport = &oauth2.Transport{
+			Source: clientCredentials.TokenSource(ctx),
+			Base:   baseTransport,
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth method: %s", method)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
+
+// newBaseTransport returns a transport which presents the client certificate and trusts the CA if configured
+func newBaseTransport(config Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLSCertFile == "" && config.TLSKeyFile == "" && config.TLSCAFile == "" {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+			return nil, fmt.Errorf("both the client certificate and key files are required for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if config.TLSCAFile != "" {
+		caBytes, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read CA file")
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no certificates found in CA file: %s", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = caPool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// tokenTransport sends a bearer token
+type tokenTransport struct {
+	token func() string
+	// watcher keeps the token up-to-date if it is read from a file
+	watcher *filewatch.Watcher
+	base    http.RoundTripper
+}
+
+// newTokenTransport returns a transport which sends the token from the config or from the token file.
+// The token file is reloaded when it changes, e.g. when the mounted secret gets rotated.
+func newTokenTransport(config Config, base http.RoundTripper) (*tokenTransport, error) {
+	if config.TokenFile != "" {
+		watcher, err := filewatch.NewWatcher(config.TokenFile, filewatch.DefaultInterval, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read token file")
+		}
+		go watcher.Run(context.Background())
+		return &tokenTransport{
+			token: func() string {
+				return strings.TrimSpace(string(watcher.Content()))
+			},
+			watcher: watcher,
+			base:    base,
+		}, nil
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("a token or token file is required for auth method: %s", MethodToken)
+	}
+	return &tokenTransport{
+		token: func() string {
+			return config.Token
+		},
+		base: base,
+	}, nil
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authReq := req.Clone(req.Context())
+	authReq.Header.Set(authorizationKeyHeader, fmt.Sprintf("Bearer %s", t.token()))
+	return t.base.RoundTrip(authReq)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Run("sends a static token", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			g.Expect(req.Header.Get(authorizationKeyHeader)).To(gomega.Equal("Bearer foo"))
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		client, err := NewHTTPClient(Config{Token: "foo"}, time.Second)
+		g.Expect(err).Should(gomega.BeNil())
+		resp, err := client.Get(srv.URL)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+	})
+
+	t.Run("reloads the token file when it changes", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		expectedToken := "foo"
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			g.Expect(req.Header.Get(authorizationKeyHeader)).To(gomega.Equal("Bearer " + expectedToken))
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "auth")
+		g.Expect(err).Should(gomega.BeNil())
+		defer func() {
+			_ = os.RemoveAll(dir)
+		}()
+		tokenFile := filepath.Join(dir, "token")
+		err = ioutil.WriteFile(tokenFile, []byte("foo\n"), 0600)
+		g.Expect(err).Should(gomega.BeNil())
+
+		client, err := NewHTTPClient(Config{TokenFile: tokenFile}, time.Second)
+		g.Expect(err).Should(gomega.BeNil())
+		resp, err := client.Get(srv.URL)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+
+		err = ioutil.WriteFile(tokenFile, []byte("bar\n"), 0600)
+		g.Expect(err).Should(gomega.BeNil())
+		_, err = client.Transport.(*tokenTransport).watcher.Check()
+		g.Expect(err).Should(gomega.BeNil())
+		expectedToken = "bar"
+		resp, err = client.Get(srv.URL)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+	})
+
+	t.Run("fetches and caches an OAuth2 token", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		tokensIssued := 0
+		tokenSrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			clientID, clientSecret, ok := req.BasicAuth()
+			g.Expect(ok).To(gomega.BeTrue())
+			g.Expect(clientID).To(gomega.Equal("client"))
+			g.Expect(clientSecret).To(gomega.Equal("secret"))
+			g.Expect(req.FormValue("grant_type")).To(gomega.Equal("client_credentials"))
+			g.Expect(req.FormValue("scope")).To(gomega.Equal("read write"))
+			tokensIssued++
+			rw.Header().Set("Content-Type", "application/json")
+			err := json.NewEncoder(rw).Encode(map[string]interface{}{
+				"access_token": "oauth2-token",
+				"token_type":   "bearer",
+				"expires_in":   3600,
+			})
+			g.Expect(err).Should(gomega.BeNil())
+		}))
+		defer tokenSrv.Close()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			g.Expect(req.Header.Get(authorizationKeyHeader)).To(gomega.Equal("Bearer oauth2-token"))
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		client, err := NewHTTPClient(Config{
+			OAuth2TokenURL:     tokenSrv.URL,
+			OAuth2ClientID:     "client",
+			OAuth2ClientSecret: "secret",
+			OAuth2Scopes:       []string{"read", "write"},
+		}, time.Second)
+		g.Expect(err).Should(gomega.BeNil())
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(srv.URL)
+			g.Expect(err).Should(gomega.BeNil())
+			g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+		}
+		g.Expect(tokensIssued).To(gomega.Equal(1))
+	})
+
+	t.Run("trusts the configured CA", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			g.Expect(req.Header.Get(authorizationKeyHeader)).To(gomega.BeEmpty())
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "auth")
+		g.Expect(err).Should(gomega.BeNil())
+		defer func() {
+			_ = os.RemoveAll(dir)
+		}()
+		caFile := filepath.Join(dir, "ca.crt")
+		caBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		err = ioutil.WriteFile(caFile, caBytes, 0600)
+		g.Expect(err).Should(gomega.BeNil())
+
+		client, err := NewHTTPClient(Config{Method: MethodNone, TLSCAFile: caFile}, time.Second)
+		g.Expect(err).Should(gomega.BeNil())
+		resp, err := client.Get(srv.URL)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+	})
+
+	t.Run("rejects incomplete configs", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		_, err := NewHTTPClient(Config{Method: MethodToken}, time.Second)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		_, err = NewHTTPClient(Config{Method: MethodOAuth2, OAuth2TokenURL: "https://foo"}, time.Second)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		_, err = NewHTTPClient(Config{Method: "foo"}, time.Second)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		_, err = NewHTTPClient(Config{TLSCertFile: "tls.crt"}, time.Second)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+	})
+}
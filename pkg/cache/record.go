@@ -2,9 +2,29 @@ package cache
 
 import "github.com/kyma-incubator/metris/pkg/edp"
 
+// Record is the state of a tracked runtime. A subaccount can have multiple runtimes.
+// It must not hold any credentials as it gets logged.
 type Record struct {
 	SubAccountID string
+	RuntimeID    string
+	InstanceID   string
 	ShootName    string
-	KubeConfig   string
-	Metric       *edp.ConsumptionMetrics
+	// Runtime is the metadata of the runtime in KEB
+	Runtime RuntimeMetadata
+	// ProviderType and KubernetesVersion are taken from the shoot of the last scrape
+	ProviderType      string
+	KubernetesVersion string
+	// Suspended is set while the runtime is suspended in KEB
+	Suspended bool
+	// RetainedVolumes are the volumes of the last scrape which are kept while the runtime is suspended or hibernated
+	RetainedVolumes edp.ProvisionedVolumes
+	Metric          *edp.ConsumptionMetrics
+}
+
+// RuntimeMetadata is the part of a runtime in KEB which is sent along with its metrics
+type RuntimeMetadata struct {
+	GlobalAccountID string
+	ServicePlanID   string
+	ServicePlanName string
+	Region          string
 }
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// ScrapeStatus is the outcome of the latest scrape and send for a runtime
+type ScrapeStatus struct {
+	SubAccountID         string     `json:"subaccount_id"`
+	RuntimeID            string     `json:"runtime_id"`
+	ShootName            string     `json:"shoot_name"`
+	LastSuccessfulScrape *time.Time `json:"last_successful_scrape,omitempty"`
+	LastError            string     `json:"last_error,omitempty"`
+	LastErrorStage       string     `json:"last_error_stage,omitempty"`
+	LastErrorTime        *time.Time `json:"last_error_time,omitempty"`
+	LastSent             *time.Time `json:"last_sent,omitempty"`
+	LastPayloadStale     bool       `json:"last_payload_stale"`
+	LastEDPStatusCode    int        `json:"last_edp_status_code,omitempty"`
+	NextScrape           *time.Time `json:"next_scrape,omitempty"`
+}
+
+// StatusStore holds the scrape status per runtime. All the methods are no-ops on a nil store.
+type StatusStore struct {
+	mu       sync.RWMutex
+	statuses map[string]ScrapeStatus
+}
+
+func NewStatusStore() *StatusStore {
+	return &StatusStore{
+		statuses: make(map[string]ScrapeStatus),
+	}
+}
+
+// Update applies updateFn to the status of a runtime and creates the status if it does not exist yet
+func (s *StatusStore) Update(runtimeID string, updateFn func(status *ScrapeStatus)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.statuses[runtimeID]
+	status.RuntimeID = runtimeID
+	updateFn(&status)
+	s.statuses[runtimeID] = status
+}
+
+// Get returns the status of a runtime
+func (s *StatusStore) Get(runtimeID string) (ScrapeStatus, bool) {
+	if s == nil {
+		return ScrapeStatus{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, found := s.statuses[runtimeID]
+	return status, found
+}
+
+// Delete deletes the status of a runtime which is not tracked anymore
+func (s *StatusStore) Delete(runtimeID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.statuses, runtimeID)
+}
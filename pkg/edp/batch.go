@@ -0,0 +1,270 @@
+package edp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// edpBatchPathFormat is the batch endpoint metris expects. EDP does not document a batch API, so batching is
+// experimental and only works against a server which implements this endpoint and BatchResponse.
+const edpBatchPathFormat = "%s/namespaces/%s/dataStreams/%s/%s/%s/batch"
+
+// BatchEvent is the event stream of a tenant in a batch
+type BatchEvent struct {
+	DataTenant string          `json:"dataTenant"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// BatchRequest is the body of a request to the batch endpoint of EDP
+type BatchRequest struct {
+	Events []BatchEvent `json:"events"`
+}
+
+// BatchEventResult is the result of an event in a batch.
+// EDP returns the results in the same order as the events of the request.
+type BatchEventResult struct {
+	DataTenant string `json:"dataTenant"`
+	Status     int    `json:"status"`
+	Error      string `json:"error,omitempty"`
+	// DeliveredVersions are the datastream versions which accepted the event
+	DeliveredVersions []string `json:"-"`
+}
+
+// BatchResponse is the body of a response from the batch endpoint of EDP
+type BatchResponse struct {
+	Results []BatchEventResult `json:"results"`
+}
+
+// SendBatch sends the event streams of multiple tenants to EDP in one request per configured schema version and
+// returns the result of every event. An event failed if it failed in any version, e.g. as it does not match the schema.
+// An error is returned if a batch as a whole was not accepted, along with the results which tell the versions
+// that accepted the events before.
+func (eClient Client) SendBatch(events []BatchEvent) ([]BatchEventResult, error) {
+	results := make([]BatchEventResult, len(events))
+	for i, event := range events {
+		results[i] = BatchEventResult{DataTenant: event.DataTenant, Status: http.StatusCreated}
+	}
+	for _, version := range eClient.Config.DataStreamVersions() {
+		// Keep the index of every event which is sent in this version
+		var versionedEvents []BatchEvent
+		var indexes []int
+		for i, event := range events {
+			if results[i].Status != http.StatusCreated {
+				continue
+			}
+			payload, err := encodeBatchEvent(event.Event, version)
+			if err != nil {
+				// Keep the versions which accepted the event already, so that they do not get it again on replay
+				results[i] = BatchEventResult{
+					DataTenant:        event.DataTenant,
+					Status:            http.StatusBadRequest,
+					Error:             err.Error(),
+					DeliveredVersions: results[i].DeliveredVersions,
+				}
+				continue
+			}
+			versionedEvents = append(versionedEvents, BatchEvent{DataTenant: event.DataTenant, Event: payload})
+			indexes = append(indexes, i)
+		}
+		if len(versionedEvents) == 0 {
+			continue
+		}
+		versionResults, err := eClient.sendBatch(version, versionedEvents)
+		if err != nil {
+			return results, err
+		}
+		for j, result := range versionResults {
+			i := indexes[j]
+			result.DeliveredVersions = results[i].DeliveredVersions
+			if result.Status == http.StatusCreated {
+				result.DeliveredVersions = append(result.DeliveredVersions, version)
+			}
+			results[i] = result
+		}
+	}
+	return results, nil
+}
+
+// encodeBatchEvent converts the consumption metrics of a batch event into a schema version
+func encodeBatchEvent(event json.RawMessage, version string) ([]byte, error) {
+	metric := ConsumptionMetrics{}
+	if err := json.Unmarshal(event, &metric); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal consumption metrics")
+	}
+	return EncodeConsumptionMetrics(metric, version)
+}
+
+// sendBatch sends the events in a schema version in one request
+func (eClient Client) sendBatch(version string, events []BatchEvent) ([]BatchEventResult, error) {
+	payload, err := json.Marshal(BatchRequest{Events: events})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to json.Marshal batch")
+	}
+
+	edpURL := fmt.Sprintf(edpBatchPathFormat,
+		eClient.Config.URL,
+		eClient.Config.Namespace,
+		eClient.Config.DataStreamName,
+		version,
+		eClient.Config.DataStreamEnv,
+	)
+	req, err := http.NewRequest(http.MethodPost, edpURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed generate batch request for EDP")
+	}
+	req.Header.Set(userAgentKeyHeader, userAgentMetris)
+	req.Header.Add(contentTypeKeyHeader, contentType)
+
+	resp, body, err := eClient.retryPolicy().Do(eClient.HttpClient, req, payload, http.StatusOK, http.StatusCreated, http.StatusMultiStatus)
+	if err != nil {
+		return nil, errors.Wrapf(toResponseError(err), "failed to POST batch to EDP")
+	}
+
+	// All the events were accepted if EDP does not tell otherwise
+	if resp.StatusCode == http.StatusCreated && len(bytes.TrimSpace(body)) == 0 {
+		results := make([]BatchEventResult, len(events))
+		for i, event := range events {
+			results[i] = BatchEventResult{DataTenant: event.DataTenant, Status: http.StatusCreated}
+		}
+		return results, nil
+	}
+
+	batchResp := new(BatchResponse)
+	if err := json.Unmarshal(body, batchResp); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal batch response from EDP")
+	}
+	if len(batchResp.Results) != len(events) {
+		return nil, fmt.Errorf("EDP returned %d results for a batch of %d events", len(batchResp.Results), len(events))
+	}
+	return batchResp.Results, nil
+}
+
+// batchItem is an event waiting to be sent in the next batch
+type batchItem struct {
+	event BatchEvent
+	done  func(statusCode int, deliveredVersions []string, err error)
+}
+
+// Batcher accumulates the event streams of multiple tenants and sends them to EDP in batches of up to Size
+// events. A batch is sent when it is full or FlushInterval passed since the last one.
+type Batcher struct {
+	Client        *Client
+	Size          int
+	FlushInterval time.Duration
+	Logger        *logrus.Logger
+
+	mu      sync.Mutex
+	pending []batchItem
+	full    chan struct{}
+}
+
+func NewBatcher(client *Client, size int, flushInterval time.Duration, logger *logrus.Logger) *Batcher {
+	return &Batcher{
+		Client:        client,
+		Size:          size,
+		FlushInterval: flushInterval,
+		Logger:        logger,
+		full:          make(chan struct{}, 1),
+	}
+}
+
+// Submit adds an event stream to the next batch. done is called with the HTTP status code EDP returned for the
+// event and the datastream versions which accepted it once the batch was sent.
+func (b *Batcher) Submit(dataTenant string, payload []byte, done func(statusCode int, deliveredVersions []string, err error)) {
+	b.mu.Lock()
+	b.pending = append(b.pending, batchItem{
+		event: BatchEvent{DataTenant: dataTenant, Event: payload},
+		done:  done,
+	})
+	isFull := len(b.pending) >= b.Size
+	b.mu.Unlock()
+
+	if isFull {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run sends the batches until the context is done and flushes the remaining events at the end
+func (b *Batcher) Run(ctx context.Context) {
+	if b == nil {
+		return
+	}
+	ticker := time.NewTicker(b.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			b.Flush()
+			return
+		case <-ticker.C:
+			b.Flush()
+		case <-b.full:
+			b.Flush()
+		}
+	}
+}
+
+// Flush sends all the pending events
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	items := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for start := 0; start < len(items); start += b.Size {
+		end := start + b.Size
+		if end > len(items) {
+			end = len(items)
+		}
+		b.send(items[start:end])
+	}
+}
+
+func (b *Batcher) send(items []batchItem) {
+	events := make([]BatchEvent, len(items))
+	for i, item := range items {
+		events[i] = item.event
+	}
+
+	results, err := b.Client.SendBatch(events)
+	if err != nil {
+		b.Logger.Errorf("failed to send batch of %d events to EDP: %v", len(items), err)
+		statusCode := 0
+		var respErr ResponseError
+		if errors.As(err, &respErr) {
+			statusCode = respErr.StatusCode
+		}
+		for i, item := range items {
+			var deliveredVersions []string
+			if results != nil {
+				deliveredVersions = results[i].DeliveredVersions
+			}
+			item.done(statusCode, deliveredVersions, err)
+		}
+		return
+	}
+
+	failed := 0
+	for i, item := range items {
+		result := results[i]
+		if result.Status != http.StatusCreated {
+			failed++
+			item.done(result.Status, result.DeliveredVersions, errors.Wrapf(ResponseError{StatusCode: result.Status, Body: result.Error},
+				"event for tenant: %s was rejected", item.event.DataTenant))
+			continue
+		}
+		item.done(result.Status, result.DeliveredVersions, nil)
+	}
+	b.Logger.Debugf("sent batch of %d events to EDP, %d failed", len(items), failed)
+}
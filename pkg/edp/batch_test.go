@@ -0,0 +1,197 @@
+package edp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+const testMetricPayload = `{"timestamp":"2021-02-01T10:00:00Z","compute":{"vm_types":[]},"networking":{}}`
+
+func TestSendBatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/%s/batch", testNamespace, testDataStreamName, testDataStreamVersion, testEnv)
+
+	// Rejects the events of the tenant bar
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		g.Expect(req.Method).To(gomega.Equal(http.MethodPost))
+		g.Expect(req.Header.Get("Authorization")).To(gomega.Equal(fmt.Sprintf("Bearer %s", testToken)))
+		batchReq := new(BatchRequest)
+		err := json.NewDecoder(req.Body).Decode(batchReq)
+		g.Expect(err).Should(gomega.BeNil())
+
+		batchResp := BatchResponse{}
+		for _, event := range batchReq.Events {
+			result := BatchEventResult{DataTenant: event.DataTenant, Status: http.StatusCreated}
+			if event.DataTenant == "bar" {
+				result.Status = http.StatusBadRequest
+				result.Error = "invalid event"
+			}
+			batchResp.Results = append(batchResp.Results, result)
+		}
+		rw.WriteHeader(http.StatusMultiStatus)
+		err = json.NewEncoder(rw).Encode(batchResp)
+		g.Expect(err).Should(gomega.BeNil())
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	edpClient, err := NewClient(NewTestConfig(srv.URL), logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	results, err := edpClient.SendBatch([]BatchEvent{
+		{DataTenant: "foo", Event: json.RawMessage(testMetricPayload)},
+		{DataTenant: "bar", Event: json.RawMessage(testMetricPayload)},
+	})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(results).To(gomega.Equal([]BatchEventResult{
+		{DataTenant: "foo", Status: http.StatusCreated, DeliveredVersions: []string{testDataStreamVersion}},
+		{DataTenant: "bar", Status: http.StatusBadRequest, Error: "invalid event"},
+	}))
+}
+
+func TestSendBatchInMultipleVersions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Version 2 of the datastream is down
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, fmt.Sprintf("/%s/2/", testDataStreamName)) {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	config := NewTestConfig(srv.URL)
+	config.DataStreamVersion = "1,2"
+	config.Timeout = time.Millisecond
+	edpClient, err := NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	// The results tell that version 1 accepted the event already
+	results, err := edpClient.SendBatch([]BatchEvent{{DataTenant: "foo", Event: json.RawMessage(testMetricPayload)}})
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	g.Expect(results).To(gomega.HaveLen(1))
+	g.Expect(results[0].DeliveredVersions).To(gomega.Equal([]string{"1"}))
+
+	batcher := NewBatcher(edpClient, 10, time.Hour, logrus.New())
+	var gotDeliveredVersions []string
+	batcher.Submit("foo", []byte(testMetricPayload), func(statusCode int, deliveredVersions []string, err error) {
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		gotDeliveredVersions = deliveredVersions
+	})
+	batcher.Flush()
+	g.Expect(gotDeliveredVersions).To(gomega.Equal([]string{"1"}))
+}
+
+func TestSendBatchWithInvalidVersion(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	config := NewTestConfig(srv.URL)
+	config.DataStreamVersion = "1,2"
+	edpClient, err := NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	// The NAT gateway IPs are only validated in version 2, the versions which accepted the event are kept
+	invalidPayload := `{"timestamp":"2021-02-01T10:00:00Z","compute":{"vm_types":[]},"networking":{"nat_gateway_ips":-1}}`
+	results, err := edpClient.SendBatch([]BatchEvent{{DataTenant: "foo", Event: json.RawMessage(invalidPayload)}})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(results).To(gomega.HaveLen(1))
+	g.Expect(results[0].Status).To(gomega.Equal(http.StatusBadRequest))
+	g.Expect(results[0].DeliveredVersions).To(gomega.Equal([]string{"1"}))
+}
+
+func TestBatcher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/%s/batch", testNamespace, testDataStreamName, testDataStreamVersion, testEnv)
+
+	var mu sync.Mutex
+	var batchSizes []int
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		batchReq := new(BatchRequest)
+		err := json.NewDecoder(req.Body).Decode(batchReq)
+		g.Expect(err).Should(gomega.BeNil())
+		mu.Lock()
+		batchSizes = append(batchSizes, len(batchReq.Events))
+		mu.Unlock()
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	edpClient, err := NewClient(NewTestConfig(srv.URL), logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	batcher := NewBatcher(edpClient, 2, time.Hour, logrus.New())
+
+	gotStatusCodes := make(map[string]int)
+	for i := 0; i < 5; i++ {
+		tenant := fmt.Sprintf("tenant-%d", i)
+		batcher.Submit(tenant, []byte(testMetricPayload), func(statusCode int, deliveredVersions []string, err error) {
+			g.Expect(err).Should(gomega.BeNil())
+			gotStatusCodes[tenant] = statusCode
+		})
+	}
+	batcher.Flush()
+
+	g.Expect(batchSizes).To(gomega.Equal([]int{2, 2, 1}))
+	g.Expect(gotStatusCodes).To(gomega.HaveLen(5))
+	for _, statusCode := range gotStatusCodes {
+		g.Expect(statusCode).To(gomega.Equal(http.StatusCreated))
+	}
+}
+
+func TestBatcherOnFailure(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/%s/batch", testNamespace, testDataStreamName, testDataStreamVersion, testEnv)
+
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	config := NewTestConfig(srv.URL)
+	config.Timeout = time.Millisecond
+	edpClient, err := NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	batcher := NewBatcher(edpClient, 10, time.Hour, logrus.New())
+
+	// Every event of a failed batch gets the error
+	failed := 0
+	for i := 0; i < 3; i++ {
+		batcher.Submit(fmt.Sprintf("tenant-%d", i), []byte(testMetricPayload), func(statusCode int, deliveredVersions []string, err error) {
+			g.Expect(err).ShouldNot(gomega.BeNil())
+			g.Expect(statusCode).To(gomega.Equal(http.StatusInternalServerError))
+			failed++
+		})
+	}
+	batcher.Flush()
+	g.Expect(failed).To(gomega.Equal(3))
+}
+
+func TestBatchConfig(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	config := NewTestConfig("http://localhost")
+	config.BatchEnabled = true
+	config.BatchSize = 10
+	config.BatchInterval = 0
+	_, err := NewClient(config, logrus.New())
+	g.Expect(err).ShouldNot(gomega.BeNil())
+
+	config.BatchInterval = time.Second
+	_, err = NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+}
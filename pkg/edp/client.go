@@ -2,12 +2,14 @@ package edp
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/kyma-incubator/metris/pkg/auth"
+	"github.com/kyma-incubator/metris/pkg/httpretry"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 
 	"github.com/pkg/errors"
 
@@ -17,36 +19,84 @@ import (
 type Client struct {
 	HttpClient *http.Client
 	Config     *Config
+	Breaker    *httpretry.Breaker
 	Logger     *logrus.Logger
 }
 
 const (
-	edpPathFormat          = "%s/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events"
-	contentType            = "application/json;charset=utf-8"
-	userAgentMetris        = "metris"
-	userAgentKeyHeader     = "User-Agent"
-	contentTypeKeyHeader   = "Content-Type"
-	authorizationKeyHeader = "Authorization"
+	edpPathFormat        = "%s/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events"
+	contentType          = "application/json;charset=utf-8"
+	userAgentMetris      = "metris"
+	userAgentKeyHeader   = "User-Agent"
+	contentTypeKeyHeader = "Content-Type"
+	// maxRetryAfter caps how long EDP may ask to wait before retrying
+	maxRetryAfter = 2 * time.Minute
 )
 
-func NewClient(config *Config, logger *logrus.Logger) *Client {
-	httpClient := &http.Client{
-		Transport: http.DefaultTransport,
-		Timeout:   config.Timeout,
+// ResponseError is returned when EDP responds with an unexpected HTTP status code
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e ResponseError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("failed to send event stream as EDP returned HTTP: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("failed to send event stream as EDP returned HTTP: %d: %s", e.StatusCode, e.Body)
+}
+
+func NewClient(config *Config, logger *logrus.Logger) (*Client, error) {
+	// EDP_TOKEN is kept as the static token for backward compatibility
+	authConfig := config.Auth
+	if authConfig.Token == "" {
+		authConfig.Token = config.Token
+	}
+	// EDP does not accept anonymous requests
+	if authConfig.AuthMethod() == auth.MethodNone {
+		authConfig.Method = auth.MethodToken
+	}
+	versions := config.DataStreamVersions()
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no datastream version for EDP")
+	}
+	for _, version := range versions {
+		if !isConsumptionSchema(version) {
+			return nil, fmt.Errorf("unknown datastream version for EDP: %s", version)
+		}
+	}
+	if config.BatchEnabled && (config.BatchSize <= 0 || config.BatchInterval <= 0) {
+		return nil, fmt.Errorf("batch size and flush interval for EDP must be positive: %d, %v", config.BatchSize, config.BatchInterval)
+	}
+	httpClient, err := auth.NewHTTPClient(authConfig, config.Timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create HTTP client for EDP")
 	}
 	return &Client{
 		HttpClient: httpClient,
 		Logger:     logger,
 		Config:     config,
-	}
+		Breaker:    httpretry.NewBreaker(config.BreakerThreshold, config.BreakerCooldown),
+	}, nil
 }
 
+// NewRequest creates a request which sends consumption metrics in the latest of the configured schema versions
 func (eClient Client) NewRequest(dataTenant string) (*http.Request, error) {
+	versions := eClient.Config.DataStreamVersions()
+	return eClient.NewRequestForDataStream(eClient.Config.DataStreamName, versions[len(versions)-1], dataTenant)
+}
+
+// NewRequestForDataStream creates a request which sends events to a version of a datastream of the same namespace.
+// The consumption metrics datastream is used if dataStream is empty.
+func (eClient Client) NewRequestForDataStream(dataStream, version, dataTenant string) (*http.Request, error) {
+	if dataStream == "" {
+		dataStream = eClient.Config.DataStreamName
+	}
 	edpURL := fmt.Sprintf(edpPathFormat,
 		eClient.Config.URL,
 		eClient.Config.Namespace,
-		eClient.Config.DataStreamName,
-		eClient.Config.DataStreamVersion,
+		dataStream,
+		version,
 		dataTenant,
 		eClient.Config.DataStreamEnv,
 	)
@@ -59,52 +109,106 @@ func (eClient Client) NewRequest(dataTenant string) (*http.Request, error) {
 
 	req.Header.Set(userAgentKeyHeader, userAgentMetris)
 	req.Header.Add(contentTypeKeyHeader, contentType)
-	req.Header.Add(authorizationKeyHeader, fmt.Sprintf("Bearer %s", eClient.Config.Token))
 
 	return req, nil
 }
 
-func (eClient Client) Send(req *http.Request, payload []byte) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	customBackoff := wait.Backoff{
-		Steps:    eClient.Config.EventRetry,
-		Duration: eClient.Config.Timeout,
-		Factor:   5.0,
-		Jitter:   0.1,
-	}
-	err = retry.OnError(customBackoff, func(err error) bool {
-		if err != nil {
-			return true
-		}
-		return false
-	}, func() (err error) {
-		req.Body = ioutil.NopCloser(bytes.NewReader(payload))
-		resp, err = eClient.HttpClient.Do(req)
+// VersionedPayload is the payload of an event in a schema version
+type VersionedPayload struct {
+	Version string
+	Payload []byte
+}
+
+// VersionedPayloads returns the payloads to send for an event of a datastream. The consumption metrics are
+// converted into every configured schema version, the other events are sent in the version they carry.
+func (eClient Client) VersionedPayloads(dataStream string, payload []byte) ([]VersionedPayload, error) {
+	if dataStream != "" && dataStream != eClient.Config.DataStreamName {
+		return []VersionedPayload{{Version: schemaVersionOf(payload), Payload: payload}}, nil
+	}
+	metric := ConsumptionMetrics{}
+	if err := json.Unmarshal(payload, &metric); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal consumption metrics")
+	}
+	var payloads []VersionedPayload
+	for _, version := range eClient.Config.DataStreamVersions() {
+		versionedPayload, err := EncodeConsumptionMetrics(metric, version)
 		if err != nil {
-			eClient.Logger.Debugf("req: %v", req)
-			eClient.Logger.Warnf("will be retried: failed to send event stream to EDP: %v", err)
-			return
+			return nil, err
 		}
+		payloads = append(payloads, VersionedPayload{Version: version, Payload: versionedPayload})
+	}
+	return payloads, nil
+}
 
-		if resp.StatusCode != http.StatusCreated {
-			non2xxErr := fmt.Errorf("failed to send event stream as EDP returned HTTP: %d", resp.StatusCode)
-			eClient.Logger.Warnf("will be retried: %v", non2xxErr)
-			err = non2xxErr
-		}
-		return
-	})
+// schemaVersionOf returns the schema version which is carried by the payload of an event, version 1 if none
+func schemaVersionOf(payload []byte) string {
+	event := struct {
+		SchemaVersion string `json:"schema_version"`
+	}{}
+	if err := json.Unmarshal(payload, &event); err != nil || event.SchemaVersion == "" {
+		return "1"
+	}
+	return event.SchemaVersion
+}
+
+// retryPolicy returns the policy for retrying the requests to EDP
+func (eClient Client) retryPolicy() httpretry.Policy {
+	return httpretry.Policy{
+		Backoff: wait.Backoff{
+			Steps:    eClient.Config.EventRetry,
+			Duration: eClient.Config.Timeout,
+			Factor:   5.0,
+			Jitter:   0.1,
+		},
+		MaxRetryAfter: maxRetryAfter,
+		Breaker:       eClient.Breaker,
+		Logger:        eClient.Logger,
+	}
+}
 
+func (eClient Client) Send(req *http.Request, payload []byte) (*http.Response, error) {
+	if eventID := eventIDOf(payload); eventID != "" {
+		req.Header.Set(EventIDHeader, eventID)
+	}
+	resp, _, err := eClient.retryPolicy().Do(eClient.HttpClient, req, payload, http.StatusCreated)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to POST event to EDP")
+		return nil, errors.Wrapf(toResponseError(err), "failed to POST event to EDP")
+	}
+	return resp, nil
+}
+
+// toResponseError converts an unexpected HTTP status code from EDP into a ResponseError
+func toResponseError(err error) error {
+	var statusErr httpretry.StatusError
+	if errors.As(err, &statusErr) {
+		return ResponseError{StatusCode: statusErr.StatusCode, Body: statusErr.Body}
 	}
+	return err
+}
 
-	defer func() {
-		err := resp.Body.Close()
-		if err != nil {
-			eClient.Logger.Warn(err)
-		}
-	}()
+// IsRetryable checks if sending an event stream which failed with the error might succeed later
+func IsRetryable(err error) bool {
+	var validationErr ValidationError
+	if errors.As(err, &validationErr) {
+		return false
+	}
+	var respErr ResponseError
+	if errors.As(err, &respErr) {
+		return httpretry.IsRetryableStatus(respErr.StatusCode)
+	}
+	return httpretry.IsRetryable(err)
+}
 
-	return resp, nil
+// IsRejected checks if EDP will never accept an event stream which failed with the error as it is invalid.
+// Other failures, e.g. an expired token, are worth replaying later.
+func IsRejected(err error) bool {
+	var validationErr ValidationError
+	if errors.As(err, &validationErr) {
+		return true
+	}
+	var respErr ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusBadRequest || respErr.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
 }
@@ -19,7 +19,7 @@ const (
 	testTenant            = "testTenant"
 	testDataStreamName    = "dataStream"
 	testNamespace         = "namespace"
-	testDataStreamVersion = "v1"
+	testDataStreamVersion = "1"
 	testToken             = "token"
 	testEnv               = "env"
 )
@@ -49,7 +49,8 @@ func TestClient(t *testing.T) {
 	edpURL, err := url.ParseRequestURI(srv.URL)
 	g.Expect(err).Should(gomega.BeNil())
 
-	edpClient := NewClient(config, logrus.New())
+	edpClient, err := NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
 	testData := []byte("foodata")
 	gotReq, err := edpClient.NewRequest(dataTenant)
 	g.Expect(err).Should(gomega.BeNil())
@@ -84,7 +85,8 @@ func TestClientRetry(t *testing.T) {
 	edpURL, err := url.ParseRequestURI(srv.URL)
 	g.Expect(err).Should(gomega.BeNil())
 
-	edpClient := NewClient(config, logrus.New())
+	edpClient, err := NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
 	testData := []byte("foodata")
 	gotReq, err := edpClient.NewRequest(dataTenant)
 	g.Expect(err).Should(gomega.BeNil())
@@ -96,6 +98,47 @@ func TestClientRetry(t *testing.T) {
 	g.Expect(countRetry).Should(gomega.Equal(expectedCountRetry))
 }
 
+func TestClientFailsFastOnClientErrors(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStreamName, testDataStreamVersion, testTenant, testEnv)
+
+	countRetry := 0
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		countRetry += 1
+		rw.WriteHeader(http.StatusBadRequest)
+		_, err := rw.Write([]byte("invalid event stream"))
+		g.Expect(err).Should(gomega.BeNil())
+	})
+
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	edpClient, err := NewClient(NewTestConfig(srv.URL), logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	gotReq, err := edpClient.NewRequest(testTenant)
+	g.Expect(err).Should(gomega.BeNil())
+
+	_, err = edpClient.Send(gotReq, []byte("foodata"))
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	g.Expect(err.Error()).Should(gomega.Equal("failed to POST event to EDP: failed to send event stream as EDP returned HTTP: 400: invalid event stream"))
+	g.Expect(IsRetryable(err)).To(gomega.BeFalse())
+	g.Expect(IsRejected(err)).To(gomega.BeTrue())
+	g.Expect(countRetry).Should(gomega.Equal(1))
+}
+
+func TestIsRejected(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	// Only invalid events are never accepted, auth and config failures are worth replaying
+	g.Expect(IsRejected(ResponseError{StatusCode: http.StatusBadRequest})).To(gomega.BeTrue())
+	g.Expect(IsRejected(ResponseError{StatusCode: http.StatusUnprocessableEntity})).To(gomega.BeTrue())
+	g.Expect(IsRejected(ValidationError{Field: "timestamp", Reason: "is required"})).To(gomega.BeTrue())
+	g.Expect(IsRejected(ResponseError{StatusCode: http.StatusUnauthorized})).To(gomega.BeFalse())
+	g.Expect(IsRejected(ResponseError{StatusCode: http.StatusForbidden})).To(gomega.BeFalse())
+	g.Expect(IsRejected(ResponseError{StatusCode: http.StatusNotFound})).To(gomega.BeFalse())
+	g.Expect(IsRejected(ResponseError{StatusCode: http.StatusServiceUnavailable})).To(gomega.BeFalse())
+	g.Expect(IsRejected(nil)).To(gomega.BeFalse())
+}
+
 func NewTestConfig(url string) *Config {
 	return &Config{
 		URL:               url,
@@ -1,14 +1,39 @@
 package edp
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/auth"
+)
 
 type Config struct {
-	URL               string        `envconfig:"EDP_URL" default:"https://input.yevents.io" required:"true"`
-	Token             string        `envconfig:"EDP_TOKEN" required:"true"`
-	Namespace         string        `envconfig:"EDP_NAMESPACE" default:"kyma-dev" required:"true"`
-	DataStreamName    string        `envconfig:"EDP_DATASTREAM_NAME" default:"consumption-metrics" required:"true"`
-	DataStreamVersion string        `envconfig:"EDP_DATASTREAM_VERSION" default:"1" required:"true"`
-	DataStreamEnv     string        `envconfig:"EDP_DATASTREAM_ENV" default:"dev" required:"true"`
-	Timeout           time.Duration `envconfig:"EDP_TIMEOUT" default:"30s"`
-	EventRetry        int           `envconfig:"EDP_RETRY" default:"3"`
+	URL                     string        `envconfig:"EDP_URL" default:"https://input.yevents.io" required:"true"`
+	Token                   string        `envconfig:"EDP_TOKEN"`
+	Namespace               string        `envconfig:"EDP_NAMESPACE" default:"kyma-dev" required:"true"`
+	DataStreamName          string        `envconfig:"EDP_DATASTREAM_NAME" default:"consumption-metrics" required:"true"`
+	DataStreamVersion       string        `envconfig:"EDP_DATASTREAM_VERSION" default:"1" required:"true"`
+	DataStreamEnv           string        `envconfig:"EDP_DATASTREAM_ENV" default:"dev" required:"true"`
+	LifecycleDataStreamName string        `envconfig:"EDP_LIFECYCLE_DATASTREAM_NAME" default:"runtime-lifecycle"`
+	UsageDataStreamName     string        `envconfig:"EDP_USAGE_DATASTREAM_NAME" default:"consumption-usage"`
+	Timeout                 time.Duration `envconfig:"EDP_TIMEOUT" default:"30s"`
+	EventRetry              int           `envconfig:"EDP_RETRY" default:"3"`
+	BreakerThreshold        int           `envconfig:"EDP_CIRCUIT_BREAKER_THRESHOLD" default:"5"`
+	BreakerCooldown         time.Duration `envconfig:"EDP_CIRCUIT_BREAKER_COOLDOWN" default:"1m"`
+	BatchEnabled            bool          `envconfig:"EDP_BATCH_ENABLED" default:"false"`
+	BatchSize               int           `envconfig:"EDP_BATCH_SIZE" default:"100"`
+	BatchInterval           time.Duration `envconfig:"EDP_BATCH_FLUSH_INTERVAL" default:"10s"`
+	Auth                    auth.Config   `envconfig:"EDP_AUTH"`
+}
+
+// DataStreamVersions returns the datastream versions the consumption metrics are sent to, each in its schema version.
+// Multiple versions can be sent side by side during a migration, e.g. 1,2.
+func (c Config) DataStreamVersions() []string {
+	var versions []string
+	for _, version := range strings.Split(c.DataStreamVersion, ",") {
+		if version = strings.TrimSpace(version); version != "" {
+			versions = append(versions, version)
+		}
+	}
+	return versions
 }
@@ -1,18 +1,43 @@
 package edp
 
 type ConsumptionMetrics struct {
-	Timestamp  string     `json:"timestamp" validate:"required"`
-	Compute    Compute    `json:"compute" validate:"required"`
-	Networking Networking `json:"networking" validate:"required"`
+	SchemaVersion string `json:"schema_version" validate:"required"`
+	// EventID is the same for every attempt to send the snapshot of a runtime in a scrape interval
+	EventID string `json:"event_id,omitempty"`
+	// The metadata identifies the runtime, so it can be priced by plan and region
+	RuntimeID         string     `json:"runtime_id,omitempty"`
+	InstanceID        string     `json:"instance_id,omitempty"`
+	GlobalAccountID   string     `json:"global_account_id,omitempty"`
+	ShootName         string     `json:"shoot_name,omitempty"`
+	ServicePlanID     string     `json:"service_plan_id,omitempty"`
+	ServicePlanName   string     `json:"service_plan_name,omitempty"`
+	Region            string     `json:"region,omitempty"`
+	ProviderType      string     `json:"provider_type,omitempty"`
+	KubernetesVersion string     `json:"kubernetes_version,omitempty"`
+	Timestamp         string     `json:"timestamp" validate:"required"`
+	Compute           Compute    `json:"compute" validate:"required"`
+	Networking        Networking `json:"networking" validate:"required"`
+	// Suspended marks the events of a runtime which is suspended or hibernated and has no compute
+	Suspended bool `json:"suspended,omitempty"`
+	// Deprovisioned marks the final event of a runtime which is not tracked anymore
+	Deprovisioned bool `json:"deprovisioned,omitempty"`
 }
 type Networking struct {
 	ProvisionedVnets int `json:"provisioned_vnets" validate:"numeric"`
-	ProvisionedIPs   int `json:"provisioned_ips" validate:"numeric"`
+	// ProvisionedIPs are the services of type LoadBalancer
+	ProvisionedIPs          int `json:"provisioned_ips" validate:"numeric"`
+	PublicLoadBalancers     int `json:"public_load_balancers" validate:"numeric"`
+	InternalLoadBalancers   int `json:"internal_load_balancers" validate:"numeric"`
+	PublicLoadBalancerIPs   int `json:"public_load_balancer_ips" validate:"numeric"`
+	InternalLoadBalancerIPs int `json:"internal_load_balancer_ips" validate:"numeric"`
+	NATGatewayIPs           int `json:"nat_gateway_ips" validate:"numeric"`
 }
 
 type VMType struct {
-	Name  string `json:"name" validate:"required"`
-	Count int    `json:"count" validate:"numeric"`
+	Name    string `json:"name" validate:"required"`
+	Count   int    `json:"count" validate:"numeric"`
+	GPUs    int    `json:"gpus,omitempty" validate:"numeric"`
+	GPUType string `json:"gpu_type,omitempty"`
 }
 
 type Compute struct {
@@ -20,6 +45,45 @@ type Compute struct {
 	ProvisionedCpus    int                `json:"provisioned_cpus" validate:"numeric"`
 	ProvisionedRAMGb   float64            `json:"provisioned_ram_gb" validate:"numeric"`
 	ProvisionedVolumes ProvisionedVolumes `json:"provisioned_volumes" validate:"required"`
+	WorkerPools        []WorkerPool       `json:"worker_pools,omitempty"`
+	NodeVolumes        []VolumeType       `json:"node_volumes,omitempty"`
+	StorageClasses     []StorageClass     `json:"storage_classes,omitempty"`
+	ProvisionedGPUs    int                `json:"provisioned_gpus" validate:"numeric"`
+	// ExtendedResources are the capacities of the extended resources of all nodes, e.g. nvidia.com/gpu
+	ExtendedResources map[string]int64 `json:"extended_resources,omitempty"`
+}
+
+// StorageClass is the storage of the cloud-backed persistent volumes of a storage class
+type StorageClass struct {
+	Name        string `json:"name,omitempty"`
+	Provisioner string `json:"provisioner,omitempty"`
+	// DiskType is the type of the disk of the cloud provider, e.g. the SKU of an Azure disk
+	DiskType string  `json:"disk_type,omitempty"`
+	Count    int     `json:"count" validate:"numeric"`
+	SizeGb   float64 `json:"size_gb" validate:"numeric"`
+	// Released counts the volumes whose claim was deleted but which are retained
+	Released int `json:"released" validate:"numeric"`
+}
+
+// VolumeType is the storage of the volumes of a type, as the types are priced differently
+type VolumeType struct {
+	Type        string `json:"type" validate:"required"`
+	Count       int    `json:"count" validate:"numeric"`
+	SizeGbTotal int64  `json:"size_gb_total" validate:"numeric"`
+}
+
+// WorkerPool is the compute of a worker pool of the shoot
+type WorkerPool struct {
+	Name         string   `json:"name" validate:"required"`
+	MachineType  string   `json:"machine_type" validate:"required"`
+	Nodes        int      `json:"nodes" validate:"numeric"`
+	Minimum      int      `json:"minimum" validate:"numeric"`
+	Maximum      int      `json:"maximum" validate:"numeric"`
+	Zones        []string `json:"zones,omitempty"`
+	VolumeType   string   `json:"volume_type,omitempty"`
+	VolumeSizeGb int64    `json:"volume_size_gb" validate:"numeric"`
+	// System marks the pools which may host the system components besides the customer workload
+	System bool `json:"system"`
 }
 
 type ProvisionedVolumes struct {
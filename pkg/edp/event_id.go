@@ -0,0 +1,44 @@
+package edp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// EventIDHeader carries the event ID of a single event, so that EDP can drop the events which were sent more than once
+const EventIDHeader = "X-Event-ID"
+
+// The variants of the consumption metrics, as a runtime can have more than one event per timestamp
+const (
+	VariantSnapshot      = "snapshot"
+	VariantSuspended     = "suspended"
+	VariantDeprovisioned = "deprovisioned"
+)
+
+// NewEventID returns a stable ID for an event which is derived from the parts which identify it, e.g. the tenant,
+// the shoot and the timestamp of a snapshot. Sending the same event again yields the same ID.
+func NewEventID(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(hash[:16])
+}
+
+// NewConsumptionEventID returns the event ID of the consumption metrics of a runtime. It is derived from the tenant,
+// the runtime, the shoot and the aligned timestamp, so every retry or rescrape in the same interval gets the same ID.
+// The variant of the event is part of it, so that the first snapshot after an unsuspension is not dropped as
+// a duplicate of the suspended event.
+func NewConsumptionEventID(subAccountID, variant string, metric ConsumptionMetrics) string {
+	return NewEventID(subAccountID, metric.RuntimeID, metric.ShootName, metric.Timestamp, variant)
+}
+
+// eventIDOf returns the event ID which is carried by the payload of an event if any
+func eventIDOf(payload []byte) string {
+	event := struct {
+		EventID string `json:"event_id"`
+	}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return ""
+	}
+	return event.EventID
+}
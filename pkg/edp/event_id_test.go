@@ -0,0 +1,69 @@
+package edp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewEventID(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	eventID := NewEventID(testTenant, "shoot", "2021-02-01T10:00:00Z")
+	g.Expect(eventID).To(gomega.HaveLen(32))
+	g.Expect(NewEventID(testTenant, "shoot", "2021-02-01T10:00:00Z")).To(gomega.Equal(eventID))
+	g.Expect(NewEventID(testTenant, "shoot", "2021-02-01T10:03:00Z")).ToNot(gomega.Equal(eventID))
+	g.Expect(NewEventID(testTenant, "other-shoot", "2021-02-01T10:00:00Z")).ToNot(gomega.Equal(eventID))
+	// The parts are separated so that they cannot be shifted into each other
+	g.Expect(NewEventID(testTenant+"shoot", "", "2021-02-01T10:00:00Z")).ToNot(gomega.Equal(eventID))
+}
+
+func TestNewConsumptionEventID(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	metric := ConsumptionMetrics{
+		RuntimeID: "runtime",
+		ShootName: "shoot",
+		Timestamp: "2021-02-01T10:00:00Z",
+		Compute:   Compute{ProvisionedCpus: 8},
+	}
+	eventID := NewConsumptionEventID(testTenant, VariantSnapshot, metric)
+	g.Expect(NewConsumptionEventID(testTenant, VariantSnapshot, metric)).To(gomega.Equal(eventID))
+
+	// The suspended event and the first snapshot after the unsuspension in the same interval differ
+	g.Expect(NewConsumptionEventID(testTenant, VariantSuspended, metric)).ToNot(gomega.Equal(eventID))
+
+	// Another runtime of the same subaccount differs
+	otherRuntime := metric
+	otherRuntime.RuntimeID = "other-runtime"
+	g.Expect(NewConsumptionEventID(testTenant, VariantSnapshot, otherRuntime)).ToNot(gomega.Equal(eventID))
+
+	// A rescrape with other values in the same interval is the same event
+	rescraped := metric
+	rescraped.Compute.ProvisionedCpus = 16
+	g.Expect(NewConsumptionEventID(testTenant, VariantSnapshot, rescraped)).To(gomega.Equal(eventID))
+}
+
+func TestSendWithEventID(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStreamName, testDataStreamVersion, testTenant, testEnv)
+	var gotEventIDs []string
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotEventIDs = append(gotEventIDs, req.Header.Get(EventIDHeader))
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	edpClient, err := NewClient(NewTestConfig(srv.URL), logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	for _, payload := range []string{`{"event_id":"foo","timestamp":"2021-02-01T10:00:00Z"}`, `{"timestamp":"2021-02-01T10:00:00Z"}`} {
+		req, err := edpClient.NewRequest(testTenant)
+		g.Expect(err).Should(gomega.BeNil())
+		_, err = edpClient.Send(req, []byte(payload))
+		g.Expect(err).Should(gomega.BeNil())
+	}
+	g.Expect(gotEventIDs).To(gomega.Equal([]string{"foo", ""}))
+}
@@ -0,0 +1,24 @@
+package edp
+
+const (
+	LifecycleProvisioned   = "provisioned"
+	LifecycleUpgraded      = "upgraded"
+	LifecycleSuspended     = "suspended"
+	LifecycleUnsuspended   = "unsuspended"
+	LifecycleDeprovisioned = "deprovisioned"
+	LifecycleShootRenamed  = "shoot_renamed"
+)
+
+// LifecycleEvent marks a transition of a runtime, e.g. when it was provisioned or suspended.
+// The timestamp is taken from the operation in KEB which caused the transition if there is one.
+type LifecycleEvent struct {
+	SchemaVersion     string `json:"schema_version" validate:"required"`
+	EventID           string `json:"event_id,omitempty"`
+	Type              string `json:"type" validate:"required"`
+	RuntimeID         string `json:"runtime_id" validate:"required"`
+	InstanceID        string `json:"instance_id,omitempty"`
+	ShootName         string `json:"shoot_name,omitempty"`
+	PreviousShootName string `json:"previous_shoot_name,omitempty"`
+	OperationID       string `json:"operation_id,omitempty"`
+	Timestamp         string `json:"timestamp" validate:"required"`
+}
@@ -0,0 +1,230 @@
+package edp
+
+//go:generate go run ../../cmd/schemagen -dir ../../schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ConsumptionSchemaV1 is the payload of the consumption metrics with the original compute and networking. It also
+	// carries the identity and the lifecycle markers of the runtime, which breaks the original payload, so that
+	// the runtimes can be told apart in every version.
+	ConsumptionSchemaV1 = "1"
+	// ConsumptionSchemaV2 adds the schema version and the details of the compute and the networking to the payload
+	ConsumptionSchemaV2 = "2"
+	// LatestConsumptionSchema is the version the consumption metrics are kept in, e.g. in the outbox
+	LatestConsumptionSchema = ConsumptionSchemaV2
+
+	LifecycleSchemaVersion = "1"
+	UsageSchemaVersion     = "1"
+
+	jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+)
+
+// ConsumptionMetricsV1 is the payload of the consumption metrics in schema version 1
+type ConsumptionMetricsV1 struct {
+	EventID           string       `json:"event_id,omitempty"`
+	RuntimeID         string       `json:"runtime_id,omitempty"`
+	InstanceID        string       `json:"instance_id,omitempty"`
+	GlobalAccountID   string       `json:"global_account_id,omitempty"`
+	ShootName         string       `json:"shoot_name,omitempty"`
+	ServicePlanID     string       `json:"service_plan_id,omitempty"`
+	ServicePlanName   string       `json:"service_plan_name,omitempty"`
+	Region            string       `json:"region,omitempty"`
+	ProviderType      string       `json:"provider_type,omitempty"`
+	KubernetesVersion string       `json:"kubernetes_version,omitempty"`
+	Timestamp         string       `json:"timestamp" validate:"required"`
+	Compute           ComputeV1    `json:"compute" validate:"required"`
+	Networking        NetworkingV1 `json:"networking" validate:"required"`
+	Suspended         bool         `json:"suspended,omitempty"`
+	Deprovisioned     bool         `json:"deprovisioned,omitempty"`
+}
+
+// ComputeV1 is the compute of the consumption metrics in schema version 1
+type ComputeV1 struct {
+	VMTypes            []VMTypeV1         `json:"vm_types" validate:"required"`
+	ProvisionedCpus    int                `json:"provisioned_cpus" validate:"numeric"`
+	ProvisionedRAMGb   float64            `json:"provisioned_ram_gb" validate:"numeric"`
+	ProvisionedVolumes ProvisionedVolumes `json:"provisioned_volumes" validate:"required"`
+}
+
+// VMTypeV1 is a VM type of the consumption metrics in schema version 1
+type VMTypeV1 struct {
+	Name  string `json:"name" validate:"required"`
+	Count int    `json:"count" validate:"numeric"`
+}
+
+// NetworkingV1 is the networking of the consumption metrics in schema version 1
+type NetworkingV1 struct {
+	ProvisionedVnets int `json:"provisioned_vnets" validate:"numeric"`
+	ProvisionedIPs   int `json:"provisioned_ips" validate:"numeric"`
+}
+
+// Schema is the payload of a version of a datastream
+type Schema struct {
+	DataStream string
+	Version    string
+	Payload    interface{}
+}
+
+// FileName is the name of the published JSON Schema
+func (s Schema) FileName() string {
+	return fmt.Sprintf("%s.v%s.json", s.DataStream, s.Version)
+}
+
+// Schemas are all the payloads Metris sends. They are published as JSON Schema.
+var Schemas = []Schema{
+	{DataStream: "consumption-metrics", Version: ConsumptionSchemaV1, Payload: ConsumptionMetricsV1{}},
+	{DataStream: "consumption-metrics", Version: ConsumptionSchemaV2, Payload: ConsumptionMetrics{}},
+	{DataStream: "runtime-lifecycle", Version: LifecycleSchemaVersion, Payload: LifecycleEvent{}},
+	{DataStream: "consumption-usage", Version: UsageSchemaVersion, Payload: UsageWindow{}},
+}
+
+// isConsumptionSchema checks if a version of the consumption metrics is known
+func isConsumptionSchema(version string) bool {
+	version = ConsumptionSchemaOf(version)
+	return version == ConsumptionSchemaV1 || version == ConsumptionSchemaV2
+}
+
+// ConsumptionSchemaOf returns the schema version of the consumption metrics which are sent to a datastream version.
+// The datastream versions used to be configured with a prefix, e.g. v1, which is still accepted.
+func ConsumptionSchemaOf(dataStreamVersion string) string {
+	return strings.TrimPrefix(strings.ToLower(dataStreamVersion), "v")
+}
+
+// EncodeConsumptionMetrics converts the metric into the schema version of a datastream version, validates it
+// and returns its payload
+func EncodeConsumptionMetrics(metric ConsumptionMetrics, version string) ([]byte, error) {
+	var event interface{}
+	switch ConsumptionSchemaOf(version) {
+	case ConsumptionSchemaV1:
+		event = ConsumptionMetricsV1{
+			EventID:           metric.EventID,
+			RuntimeID:         metric.RuntimeID,
+			InstanceID:        metric.InstanceID,
+			GlobalAccountID:   metric.GlobalAccountID,
+			ShootName:         metric.ShootName,
+			ServicePlanID:     metric.ServicePlanID,
+			ServicePlanName:   metric.ServicePlanName,
+			Region:            metric.Region,
+			ProviderType:      metric.ProviderType,
+			KubernetesVersion: metric.KubernetesVersion,
+			Suspended:         metric.Suspended,
+			Deprovisioned:     metric.Deprovisioned,
+			Timestamp:         metric.Timestamp,
+			Compute: ComputeV1{
+				VMTypes:            vmTypesV1(metric.Compute.VMTypes),
+				ProvisionedCpus:    metric.Compute.ProvisionedCpus,
+				ProvisionedRAMGb:   metric.Compute.ProvisionedRAMGb,
+				ProvisionedVolumes: metric.Compute.ProvisionedVolumes,
+			},
+			Networking: NetworkingV1{
+				ProvisionedVnets: metric.Networking.ProvisionedVnets,
+				ProvisionedIPs:   metric.Networking.ProvisionedIPs,
+			},
+		}
+	case ConsumptionSchemaV2:
+		metric.SchemaVersion = ConsumptionSchemaV2
+		event = metric
+	default:
+		return nil, fmt.Errorf("unknown schema version of consumption metrics: %s", version)
+	}
+	if err := Validate(event); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to json.Marshal consumption metrics")
+	}
+	return payload, nil
+}
+
+func vmTypesV1(vmTypes []VMType) []VMTypeV1 {
+	if vmTypes == nil {
+		return nil
+	}
+	vmTypesV1 := make([]VMTypeV1, 0, len(vmTypes))
+	for _, vmType := range vmTypes {
+		vmTypesV1 = append(vmTypesV1, VMTypeV1{Name: vmType.Name, Count: vmType.Count})
+	}
+	return vmTypesV1
+}
+
+// JSONSchema generates the JSON Schema of a payload from its Go type. The fields which are always sent or
+// are required by the validate tags are required, numeric fields must not be negative.
+func JSONSchema(schema Schema) ([]byte, error) {
+	jsonSchema := typeSchema(reflect.TypeOf(schema.Payload))
+	jsonSchema["$schema"] = jsonSchemaDraft
+	jsonSchema["$id"] = schema.FileName()
+	jsonSchema["title"] = fmt.Sprintf("%s version %s", schema.DataStream, schema.Version)
+	schemaBytes, err := json.MarshalIndent(jsonSchema, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to json.Marshal JSON Schema")
+	}
+	return append(schemaBytes, '\n'), nil
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, omitEmpty := jsonName(field)
+			if name == jsonIgnoredName {
+				continue
+			}
+			property := typeSchema(field.Type)
+			rules := strings.Split(field.Tag.Get(validateTag), ",")
+			for _, rule := range rules {
+				if rule == ruleNumeric {
+					property["minimum"] = 0
+				}
+				if rule == ruleRequired && field.Type.Kind() == reflect.String {
+					property["minLength"] = 1
+				}
+			}
+			properties[name] = property
+			if !omitEmpty || hasRule(rules, ruleRequired) {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{}
+}
+
+func hasRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
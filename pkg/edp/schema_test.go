@@ -0,0 +1,181 @@
+package edp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestMetric() ConsumptionMetrics {
+	return ConsumptionMetrics{
+		EventID:   "event",
+		RuntimeID: "runtime",
+		Timestamp: "2021-02-01T10:00:00Z",
+		Compute: Compute{
+			VMTypes:          []VMType{{Name: "standard_d8_v3", Count: 3}},
+			ProvisionedCpus:  24,
+			ProvisionedRAMGb: 96,
+		},
+		Networking: Networking{ProvisionedVnets: 1, ProvisionedIPs: 3},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	testCases := []struct {
+		name          string
+		mutate        func(metric *ConsumptionMetrics)
+		expectedField string
+	}{
+		{
+			name:   "valid",
+			mutate: func(metric *ConsumptionMetrics) {},
+		},
+		{
+			name:          "missing timestamp",
+			mutate:        func(metric *ConsumptionMetrics) { metric.Timestamp = "" },
+			expectedField: "timestamp",
+		},
+		{
+			name:          "missing vm types",
+			mutate:        func(metric *ConsumptionMetrics) { metric.Compute.VMTypes = nil },
+			expectedField: "compute.vm_types",
+		},
+		{
+			name:          "vm type without name",
+			mutate:        func(metric *ConsumptionMetrics) { metric.Compute.VMTypes[0].Name = "" },
+			expectedField: "compute.vm_types[0].name",
+		},
+		{
+			name:          "negative count",
+			mutate:        func(metric *ConsumptionMetrics) { metric.Networking.ProvisionedIPs = -1 },
+			expectedField: "networking.provisioned_ips",
+		},
+		{
+			name:          "infinite ram",
+			mutate:        func(metric *ConsumptionMetrics) { metric.Compute.ProvisionedRAMGb = math.Inf(1) },
+			expectedField: "compute.provisioned_ram_gb",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metric := newTestMetric()
+			metric.SchemaVersion = ConsumptionSchemaV2
+			tc.mutate(&metric)
+			err := Validate(metric)
+			if tc.expectedField == "" {
+				g.Expect(err).Should(gomega.BeNil())
+				return
+			}
+			g.Expect(err).To(gomega.BeAssignableToTypeOf(ValidationError{}))
+			g.Expect(err.(ValidationError).Field).To(gomega.Equal(tc.expectedField))
+			g.Expect(IsRetryable(err)).To(gomega.BeFalse())
+		})
+	}
+}
+
+func TestEncodeConsumptionMetrics(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t.Run("version 1 keeps the identity and the lifecycle of the runtime", func(t *testing.T) {
+		metric := newTestMetric()
+		metric.Deprovisioned = true
+		payload, err := EncodeConsumptionMetrics(metric, ConsumptionSchemaV1)
+		g.Expect(err).Should(gomega.BeNil())
+		fields := map[string]interface{}{}
+		g.Expect(json.Unmarshal(payload, &fields)).Should(gomega.Succeed())
+		g.Expect(fields).To(gomega.HaveLen(6))
+		g.Expect(fields).To(gomega.HaveKeyWithValue("event_id", "event"))
+		g.Expect(fields).To(gomega.HaveKeyWithValue("runtime_id", "runtime"))
+		g.Expect(fields).To(gomega.HaveKeyWithValue("deprovisioned", true))
+		g.Expect(fields).To(gomega.HaveKey("timestamp"))
+		g.Expect(fields).ToNot(gomega.HaveKey("schema_version"))
+		g.Expect(eventIDOf(payload)).To(gomega.Equal("event"))
+
+		// The former notation of the datastream versions is accepted
+		legacyPayload, err := EncodeConsumptionMetrics(metric, "v1")
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(legacyPayload).To(gomega.Equal(payload))
+
+		// The GPUs of the VM types are only sent in version 2
+		metric = newTestMetric()
+		metric.Compute.VMTypes[0].GPUs = 1
+		payload, err = EncodeConsumptionMetrics(metric, ConsumptionSchemaV1)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(string(payload)).ToNot(gomega.ContainSubstring("gpus"))
+	})
+
+	t.Run("version 2 carries its schema version", func(t *testing.T) {
+		payload, err := EncodeConsumptionMetrics(newTestMetric(), ConsumptionSchemaV2)
+		g.Expect(err).Should(gomega.BeNil())
+		metric := ConsumptionMetrics{}
+		g.Expect(json.Unmarshal(payload, &metric)).Should(gomega.Succeed())
+		g.Expect(metric.SchemaVersion).To(gomega.Equal(ConsumptionSchemaV2))
+		g.Expect(metric.RuntimeID).To(gomega.Equal("runtime"))
+	})
+
+	t.Run("invalid metric", func(t *testing.T) {
+		metric := newTestMetric()
+		metric.Timestamp = ""
+		_, err := EncodeConsumptionMetrics(metric, ConsumptionSchemaV2)
+		g.Expect(err).To(gomega.BeAssignableToTypeOf(ValidationError{}))
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := EncodeConsumptionMetrics(newTestMetric(), "3")
+		g.Expect(err).ShouldNot(gomega.BeNil())
+	})
+}
+
+func TestVersionedPayloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	config := NewTestConfig("http://localhost")
+	config.DataStreamVersion = "1,2"
+	edpClient, err := NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	payload, err := EncodeConsumptionMetrics(newTestMetric(), LatestConsumptionSchema)
+	g.Expect(err).Should(gomega.BeNil())
+	payloads, err := edpClient.VersionedPayloads(testDataStreamName, payload)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(payloads).To(gomega.HaveLen(2))
+	g.Expect(payloads[0].Version).To(gomega.Equal(ConsumptionSchemaV1))
+	g.Expect(payloads[1].Version).To(gomega.Equal(ConsumptionSchemaV2))
+	g.Expect(payloads[1].Payload).To(gomega.Equal(payload))
+
+	// The events of other datastreams are sent in the version they carry
+	payloads, err = edpClient.VersionedPayloads("otherStream", []byte(`{"schema_version":"3"}`))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(payloads).To(gomega.Equal([]VersionedPayload{{Version: "3", Payload: []byte(`{"schema_version":"3"}`)}}))
+
+	// The datastream versions are kept as configured
+	config.DataStreamVersion = "v1"
+	edpClient, err = NewClient(config, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	payloads, err = edpClient.VersionedPayloads(testDataStreamName, payload)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(payloads).To(gomega.HaveLen(1))
+	g.Expect(payloads[0].Version).To(gomega.Equal("v1"))
+	g.Expect(string(payloads[0].Payload)).ToNot(gomega.ContainSubstring("schema_version"))
+
+	config.DataStreamVersion = "1,3"
+	_, err = NewClient(config, logrus.New())
+	g.Expect(err).ShouldNot(gomega.BeNil())
+}
+
+func TestPublishedSchemas(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for _, schema := range Schemas {
+		expected, err := JSONSchema(schema)
+		g.Expect(err).Should(gomega.BeNil())
+		published, err := ioutil.ReadFile(filepath.Join("..", "..", "schema", schema.FileName()))
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(string(published)).To(gomega.Equal(string(expected)), "schema/%s is outdated, run go generate ./pkg/edp", schema.FileName())
+	}
+}
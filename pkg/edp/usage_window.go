@@ -0,0 +1,19 @@
+package edp
+
+// UsageWindow is the consumption of all the runtimes of a tenant integrated over a fixed time window
+type UsageWindow struct {
+	SchemaVersion  string  `json:"schema_version" validate:"required"`
+	EventID        string  `json:"event_id,omitempty"`
+	WindowStart    string  `json:"window_start" validate:"required"`
+	WindowEnd      string  `json:"window_end" validate:"required"`
+	CPUHours       float64 `json:"cpu_hours" validate:"numeric"`
+	RAMGbHours     float64 `json:"ram_gb_hours" validate:"numeric"`
+	StorageGbHours float64 `json:"storage_gb_hours" validate:"numeric"`
+	IPHours        float64 `json:"ip_hours" validate:"numeric"`
+	// RuntimeHours is the time covered by the snapshots of the runtimes
+	RuntimeHours float64 `json:"runtime_hours" validate:"numeric"`
+	// GapHours is the time in which the runtimes existed but no snapshots were taken, e.g. as their scrapes failed
+	GapHours float64 `json:"gap_hours" validate:"numeric"`
+	// Partial marks a window which has gaps or which started before Metris did
+	Partial bool `json:"partial,omitempty"`
+}
@@ -0,0 +1,120 @@
+package edp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+const (
+	validateTag     = "validate"
+	ruleRequired    = "required"
+	ruleNumeric     = "numeric"
+	jsonTag         = "json"
+	jsonOmitEmpty   = "omitempty"
+	jsonIgnoredName = "-"
+)
+
+// ValidationError is returned for an event which does not match its schema. EDP would reject it anyway.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("invalid event: %s %s", e.Field, e.Reason)
+}
+
+// Validate checks an event against the validate tags of its fields:
+// required fields must not be empty and numeric fields must be finite and not negative.
+func Validate(event interface{}) error {
+	return validateValue(reflect.ValueOf(event), "")
+}
+
+func validateValue(value reflect.Value, path string) error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return validateValue(value.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			name, _ := jsonName(field)
+			if name == jsonIgnoredName {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			fieldValue := value.Field(i)
+			for _, rule := range strings.Split(field.Tag.Get(validateTag), ",") {
+				if err := checkRule(rule, fieldValue, fieldPath); err != nil {
+					return err
+				}
+			}
+			if err := validateValue(fieldValue, fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkRule(rule string, value reflect.Value, path string) error {
+	switch rule {
+	case ruleRequired:
+		switch value.Kind() {
+		case reflect.String:
+			if value.Len() == 0 {
+				return ValidationError{Field: path, Reason: "is required"}
+			}
+		case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+			if value.IsNil() {
+				return ValidationError{Field: path, Reason: "is required"}
+			}
+		}
+	case ruleNumeric:
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if value.Int() < 0 {
+				return ValidationError{Field: path, Reason: "must not be negative"}
+			}
+		case reflect.Float32, reflect.Float64:
+			number := value.Float()
+			if math.IsNaN(number) || math.IsInf(number, 0) {
+				return ValidationError{Field: path, Reason: "must be a finite number"}
+			}
+			if number < 0 {
+				return ValidationError{Field: path, Reason: "must not be negative"}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonName returns the name of a field in the payload and if it is omitted when empty
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := strings.Split(field.Tag.Get(jsonTag), ",")
+	name := tag[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, option := range tag[1:] {
+		if option == jsonOmitEmpty {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
@@ -0,0 +1,103 @@
+package filewatch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is how often a mounted file is checked for changes by default.
+// The kubelet takes up to a minute to update a mounted secret anyway.
+const DefaultInterval = 30 * time.Second
+
+// Watcher keeps the content of a file up-to-date and calls the handlers when it changes.
+// It polls the file instead of relying on inotify as secret volumes are updated by swapping symlinks.
+type Watcher struct {
+	Path     string
+	Interval time.Duration
+	Logger   *logrus.Logger
+
+	mu       sync.RWMutex
+	content  []byte
+	handlers []func(content []byte)
+}
+
+// NewWatcher reads the file and returns a watcher for it
+func NewWatcher(path string, interval time.Duration, logger *logrus.Logger) (*Watcher, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read file: %s", path)
+	}
+	return &Watcher{
+		Path:     path,
+		Interval: interval,
+		Logger:   logger,
+		content:  content,
+	}, nil
+}
+
+// Content returns the latest content of the file
+func (w *Watcher) Content() []byte {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.content
+}
+
+// OnChange registers a handler which is called with the new content whenever the file changes
+func (w *Watcher) OnChange(handler func(content []byte)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Check reads the file again and calls the handlers if it changed. An empty file is ignored
+// as it is most likely being written.
+func (w *Watcher) Check() (bool, error) {
+	content, err := ioutil.ReadFile(w.Path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read file: %s", w.Path)
+	}
+
+	w.mu.Lock()
+	if len(content) == 0 || bytes.Equal(content, w.content) {
+		w.mu.Unlock()
+		return false, nil
+	}
+	w.content = content
+	handlers := make([]func(content []byte), len(w.handlers))
+	copy(handlers, w.handlers)
+	w.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(content)
+	}
+	return true, nil
+}
+
+// Run checks the file every Interval until the context is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := w.Check()
+			if err != nil {
+				if w.Logger != nil {
+					w.Logger.Warnf("failed to check for changes: %v", err)
+				}
+				continue
+			}
+			if changed && w.Logger != nil {
+				w.Logger.Infof("reloaded file: %s", w.Path)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package filewatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCheck(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "filewatch")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// Mimic a secret volume where the file is a symlink into a versioned dir
+	path := filepath.Join(dir, "token")
+	g.Expect(writeVersion(dir, "v1", "foo")).Should(gomega.BeNil())
+	g.Expect(os.Symlink(filepath.Join(dir, "v1", "token"), path)).Should(gomega.BeNil())
+
+	watcher, err := NewWatcher(path, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(string(watcher.Content())).To(gomega.Equal("foo"))
+
+	var gotContents []string
+	watcher.OnChange(func(content []byte) {
+		gotContents = append(gotContents, string(content))
+	})
+
+	changed, err := watcher.Check()
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(changed).To(gomega.BeFalse())
+
+	g.Expect(writeVersion(dir, "v2", "bar")).Should(gomega.BeNil())
+	g.Expect(os.Remove(path)).Should(gomega.BeNil())
+	g.Expect(os.Symlink(filepath.Join(dir, "v2", "token"), path)).Should(gomega.BeNil())
+
+	changed, err = watcher.Check()
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(changed).To(gomega.BeTrue())
+	g.Expect(string(watcher.Content())).To(gomega.Equal("bar"))
+	g.Expect(gotContents).To(gomega.Equal([]string{"bar"}))
+
+	// An empty file keeps the old content
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "v2", "token"), nil, 0600)).Should(gomega.BeNil())
+	changed, err = watcher.Check()
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(changed).To(gomega.BeFalse())
+	g.Expect(string(watcher.Content())).To(gomega.Equal("bar"))
+}
+
+func writeVersion(dir, version, content string) error {
+	if err := os.MkdirAll(filepath.Join(dir, version), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, version, "token"), []byte(content), 0600)
+}
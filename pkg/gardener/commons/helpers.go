@@ -1,8 +1,11 @@
 package commons
 
 import (
+	"github.com/kyma-incubator/metris/options"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -13,6 +16,21 @@ func GetGardenerKubeconfig(secretPath string) clientcmd.ClientConfig {
 	return kubeConfig
 }
 
+// NewResourceClient returns a client for a resource in the gardener namespace using the kubeconfig at GardenerSecretPath
+func NewResourceClient(opts *options.Options, gvr schema.GroupVersionResource) (dynamic.ResourceInterface, error) {
+	k8sConfig := GetGardenerKubeconfig(opts.GardenerSecretPath)
+	clientCfg, err := k8sConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig := dynamic.ConfigFor(clientCfg)
+	dynClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dynClient.Resource(gvr).Namespace(opts.GardenerNamespace), nil
+}
+
 func SetupSchemeOrDie() (*runtime.Scheme, error) {
 	scheme := runtime.NewScheme()
 	if err := corev1.AddToScheme(scheme); err != nil {
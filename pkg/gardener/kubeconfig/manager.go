@@ -0,0 +1,146 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	gardenersecret "github.com/kyma-incubator/metris/pkg/gardener/secret"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	shootKubeconfigKey = "kubeconfig"
+	watchRetryInterval = 10 * time.Second
+)
+
+// Manager caches the kubeconfigs of the shoots and keeps them up-to-date when the credentials are rotated.
+// The kubeconfigs are kept here so that they never end up in the records of the cache.
+type Manager struct {
+	SecretClient *gardenersecret.Client
+	Logger       *logrus.Logger
+
+	mu          sync.RWMutex
+	kubeconfigs map[string]string
+}
+
+func NewManager(secretClient *gardenersecret.Client, logger *logrus.Logger) *Manager {
+	return &Manager{
+		SecretClient: secretClient,
+		Logger:       logger,
+		kubeconfigs:  make(map[string]string),
+	}
+}
+
+// Get returns the kubeconfig of a shoot from the cache or fetches it from the shoot kubeconfig secret
+func (m *Manager) Get(ctx context.Context, shootName string) (string, error) {
+	m.mu.RLock()
+	kubeconfig, found := m.kubeconfigs[shootName]
+	m.mu.RUnlock()
+	if found {
+		return kubeconfig, nil
+	}
+	return m.fetch(ctx, shootName)
+}
+
+// Refresh drops the cached kubeconfig of a shoot and fetches it again, e.g. when the SKR API server rejected it
+func (m *Manager) Refresh(ctx context.Context, shootName string) (string, error) {
+	m.Invalidate(shootName)
+	return m.fetch(ctx, shootName)
+}
+
+// Invalidate drops the cached kubeconfig of a shoot, e.g. when it is not tracked anymore. It is a no-op on a nil manager.
+func (m *Manager) Invalidate(shootName string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.kubeconfigs, shootName)
+}
+
+func (m *Manager) fetch(ctx context.Context, shootName string) (string, error) {
+	secret, err := m.SecretClient.Get(ctx, shootName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get kubeconfig secret for shoot: %s", shootName)
+	}
+
+	kubeconfig := string(secret.Data[shootKubeconfigKey])
+	if kubeconfig == "" {
+		return "", fmt.Errorf("kubeconfig for shoot: %s not found", shootName)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kubeconfigs[shootName] = kubeconfig
+	return kubeconfig, nil
+}
+
+// Watch watches the shoot kubeconfig secrets and updates the cached kubeconfigs when they are rotated or deleted.
+// It blocks until the context is done.
+func (m *Manager) Watch(ctx context.Context) {
+	for {
+		watcher, err := m.SecretClient.Watch(ctx)
+		if err != nil {
+			m.Logger.Errorf("failed to watch shoot kubeconfig secrets: %v", err)
+		} else {
+			m.handleEvents(ctx, watcher)
+			watcher.Stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+			m.Logger.Debugf("restarting watch for shoot kubeconfig secrets")
+		}
+	}
+}
+
+func (m *Manager) handleEvents(ctx context.Context, watcher watch.Interface) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			m.handleEvent(event)
+		}
+	}
+}
+
+func (m *Manager) handleEvent(event watch.Event) {
+	if event.Type != watch.Modified && event.Type != watch.Deleted {
+		return
+	}
+	secret, err := gardenersecret.ConvertEventObjToSecret(event.Object)
+	if err != nil {
+		m.Logger.Warnf("failed to convert watched object to a secret: %v", err)
+		return
+	}
+	shootName, ok := gardenersecret.ShootNameFromSecretName(secret.Name)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only the kubeconfigs which are already in use are kept up-to-date
+	if _, found := m.kubeconfigs[shootName]; !found {
+		return
+	}
+	kubeconfig := string(secret.Data[shootKubeconfigKey])
+	if event.Type == watch.Deleted || kubeconfig == "" {
+		delete(m.kubeconfigs, shootName)
+		m.Logger.Debugf("dropped kubeconfig for shoot: %s", shootName)
+		return
+	}
+	m.kubeconfigs[shootName] = kubeconfig
+	m.Logger.Debugf("updated rotated kubeconfig for shoot: %s", shootName)
+}
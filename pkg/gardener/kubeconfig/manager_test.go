@@ -0,0 +1,133 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/gardener/commons"
+	gardenersecret "github.com/kyma-incubator/metris/pkg/gardener/secret"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const (
+	timeout   = 5 * time.Second
+	shootName = "foo-shoot"
+)
+
+func TestGetAndRefresh(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	secret := metristesting.NewSecret(shootName, "foo-kubeconfig")
+	secretClient, err := NewFakeSecretClient(secret)
+	g.Expect(err).Should(gomega.BeNil())
+	manager := NewManager(secretClient, logrus.New())
+
+	gotKubeconfig, err := manager.Get(ctx, shootName)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotKubeconfig).To(gomega.Equal("foo-kubeconfig"))
+
+	// Rotate the kubeconfig without the manager noticing it
+	secret.Data["kubeconfig"] = []byte("bar-kubeconfig")
+	err = updateSecret(ctx, secretClient, secret)
+	g.Expect(err).Should(gomega.BeNil())
+
+	gotKubeconfig, err = manager.Get(ctx, shootName)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotKubeconfig).To(gomega.Equal("foo-kubeconfig"))
+
+	gotKubeconfig, err = manager.Refresh(ctx, shootName)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotKubeconfig).To(gomega.Equal("bar-kubeconfig"))
+
+	_, err = manager.Get(ctx, "doesnotexist-shoot")
+	g.Expect(err).ShouldNot(gomega.BeNil())
+
+	emptySecret := metristesting.NewSecret("empty-shoot", "")
+	emptySecretClient, err := NewFakeSecretClient(emptySecret)
+	g.Expect(err).Should(gomega.BeNil())
+	_, err = NewManager(emptySecretClient, logrus.New()).Get(ctx, "empty-shoot")
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	g.Expect(err.Error()).To(gomega.Equal("kubeconfig for shoot: empty-shoot not found"))
+}
+
+func TestWatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	secret := metristesting.NewSecret(shootName, "foo-kubeconfig")
+	secretClient, err := NewFakeSecretClient(secret)
+	g.Expect(err).Should(gomega.BeNil())
+	manager := NewManager(secretClient, logrus.New())
+
+	_, err = manager.Get(ctx, shootName)
+	g.Expect(err).Should(gomega.BeNil())
+
+	go manager.Watch(ctx)
+
+	// Rotate the kubeconfig
+	g.Eventually(func() string {
+		secret.Data["kubeconfig"] = []byte("bar-kubeconfig")
+		if err := updateSecret(ctx, secretClient, secret); err != nil {
+			return err.Error()
+		}
+		return cachedKubeconfig(manager, shootName)
+	}, timeout).Should(gomega.Equal("bar-kubeconfig"))
+
+	// Delete the kubeconfig
+	err = secretClient.ResourceClient.Delete(ctx, secret.Name, metaV1.DeleteOptions{})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Eventually(func() string {
+		return cachedKubeconfig(manager, shootName)
+	}, timeout).Should(gomega.BeEmpty())
+}
+
+func cachedKubeconfig(manager *Manager, shootName string) string {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	return manager.kubeconfigs[shootName]
+}
+
+func updateSecret(ctx context.Context, secretClient *gardenersecret.Client, secret *corev1.Secret) error {
+	secretUnstructured, err := toUnstructured(secret)
+	if err != nil {
+		return err
+	}
+	_, err = secretClient.ResourceClient.Update(ctx, secretUnstructured, metaV1.UpdateOptions{})
+	return err
+}
+
+func toUnstructured(secret *corev1.Secret) (*unstructured.Unstructured, error) {
+	unstructuredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		return nil, err
+	}
+	secretUnstructured := &unstructured.Unstructured{Object: unstructuredMap}
+	secretUnstructured.SetGroupVersionKind(gardenersecret.GroupVersionKind())
+	return secretUnstructured, nil
+}
+
+func NewFakeSecretClient(secret *corev1.Secret) (*gardenersecret.Client, error) {
+	scheme, err := commons.SetupSchemeOrDie()
+	if err != nil {
+		return nil, err
+	}
+	secretUnstructured, err := toUnstructured(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, secretUnstructured)
+	nsResourceClient := dynamicClient.Resource(gardenersecret.GroupVersionResource()).Namespace("default")
+
+	return &gardenersecret.Client{ResourceClient: nsResourceClient}, nil
+}
@@ -3,6 +3,8 @@ package secret
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
@@ -13,34 +15,76 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 )
 
+const kubeconfigSecretSuffix = ".kubeconfig"
+
 type Client struct {
 	ResourceClient dynamic.ResourceInterface
+
+	// mu guards ResourceClient as it is rebuilt when the Gardener kubeconfig gets rotated
+	mu sync.RWMutex
 }
 
 func NewClient(opts *options.Options) (*Client, error) {
-	k8sConfig := gardenercommons.GetGardenerKubeconfig(opts.GardenerSecretPath)
-	clientCfg, err := k8sConfig.ClientConfig()
+	resourceClient, err := gardenercommons.NewResourceClient(opts, GroupVersionResource())
 	if err != nil {
 		return nil, err
 	}
-	restConfig := dynamic.ConfigFor(clientCfg)
-	dynClient := dynamic.NewForConfigOrDie(restConfig)
-	resourceClient := dynClient.Resource(GroupVersionResource()).Namespace(opts.GardenerNamespace)
 	return &Client{ResourceClient: resourceClient}, nil
 }
 
-func (c Client) Get(ctx context.Context, shootName string) (*corev1.Secret, error) {
-	shootKubeconfigName := fmt.Sprintf("%s.kubeconfig", shootName)
-	unstructuredSecret, err := c.ResourceClient.Get(ctx, shootKubeconfigName, metaV1.GetOptions{})
+// Reload rebuilds the client with the current Gardener kubeconfig
+func (c *Client) Reload(opts *options.Options) error {
+	resourceClient, err := gardenercommons.NewResourceClient(opts, GroupVersionResource())
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ResourceClient = resourceClient
+	return nil
+}
+
+func (c *Client) resourceClient() dynamic.ResourceInterface {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ResourceClient
+}
+
+func (c *Client) Get(ctx context.Context, shootName string) (*corev1.Secret, error) {
+	shootKubeconfigName := fmt.Sprintf("%s%s", shootName, kubeconfigSecretSuffix)
+	unstructuredSecret, err := c.resourceClient().Get(ctx, shootKubeconfigName, metaV1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return convertRuntimeObjToSecret(unstructuredSecret)
 }
 
+// Watch watches all the secrets in the gardener namespace
+func (c *Client) Watch(ctx context.Context) (watch.Interface, error) {
+	return c.resourceClient().Watch(ctx, metaV1.ListOptions{})
+}
+
+// ConvertEventObjToSecret converts the object of a watch event to a secret
+func ConvertEventObjToSecret(obj k8sruntime.Object) (*corev1.Secret, error) {
+	unstructuredSecret, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("bad object from watch event, could not cast to unstructured obj")
+	}
+	return convertRuntimeObjToSecret(unstructuredSecret)
+}
+
+// ShootNameFromSecretName returns the shoot name for the name of a shoot kubeconfig secret
+func ShootNameFromSecretName(secretName string) (string, bool) {
+	if !strings.HasSuffix(secretName, kubeconfigSecretSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(secretName, kubeconfigSecretSuffix), true
+}
+
 func convertRuntimeObjToSecret(unstructuredSecret *unstructured.Unstructured) (*corev1.Secret, error) {
 	secret := new(corev1.Secret)
 	err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(unstructuredSecret.Object, secret)
@@ -2,8 +2,13 @@ package secret
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/kyma-incubator/metris/options"
 	"github.com/kyma-incubator/metris/pkg/gardener/commons"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -45,6 +50,58 @@ func TestGet(t *testing.T) {
 	g.Expect(k8sErrors.IsNotFound(err)).To(gomega.BeTrue())
 }
 
+func TestReload(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "gardener")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	opts := &options.Options{
+		GardenerSecretPath: filepath.Join(dir, "kubeconfig"),
+		GardenerNamespace:  "default",
+	}
+
+	err = ioutil.WriteFile(opts.GardenerSecretPath, []byte(fmt.Sprintf(kubeconfigFormat, "foo")), 0600)
+	g.Expect(err).Should(gomega.BeNil())
+	client, err := NewClient(opts)
+	g.Expect(err).Should(gomega.BeNil())
+	oldResourceClient := client.resourceClient()
+
+	// The rotated kubeconfig gets a new client
+	err = ioutil.WriteFile(opts.GardenerSecretPath, []byte(fmt.Sprintf(kubeconfigFormat, "bar")), 0600)
+	g.Expect(err).Should(gomega.BeNil())
+	err = client.Reload(opts)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(client.resourceClient()).NotTo(gomega.BeIdenticalTo(oldResourceClient))
+
+	// A broken kubeconfig keeps the old client
+	reloadedResourceClient := client.resourceClient()
+	err = ioutil.WriteFile(opts.GardenerSecretPath, []byte("foo"), 0600)
+	g.Expect(err).Should(gomega.BeNil())
+	err = client.Reload(opts)
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	g.Expect(client.resourceClient()).To(gomega.BeIdenticalTo(reloadedResourceClient))
+}
+
+const kubeconfigFormat = `apiVersion: v1
+kind: Config
+clusters:
+- name: garden
+  cluster:
+    server: https://%s.example.com
+contexts:
+- name: garden
+  context:
+    cluster: garden
+    user: garden
+current-context: garden
+users:
+- name: garden
+  user:
+    token: token
+`
+
 func NewFakeClient(secret *corev1.Secret) (dynamic.ResourceInterface, error) {
 	scheme, err := commons.SetupSchemeOrDie()
 	if err != nil {
@@ -2,6 +2,7 @@ package shoot
 
 import (
 	"context"
+	"sync"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
@@ -16,22 +17,39 @@ import (
 
 type Client struct {
 	ResourceClient dynamic.ResourceInterface
+
+	// mu guards ResourceClient as it is rebuilt when the Gardener kubeconfig gets rotated
+	mu sync.RWMutex
 }
 
 func NewClient(opts *options.Options) (*Client, error) {
-	k8sConfig := gardenercommons.GetGardenerKubeconfig(opts.GardenerSecretPath)
-	client, err := k8sConfig.ClientConfig()
+	resourceClient, err := gardenercommons.NewResourceClient(opts, GroupVersionResource())
 	if err != nil {
 		return nil, err
 	}
-	restConfig := dynamic.ConfigFor(client)
-	dynClient := dynamic.NewForConfigOrDie(restConfig)
-	resourceClient := dynClient.Resource(GroupVersionResource()).Namespace(opts.GardenerNamespace)
 	return &Client{ResourceClient: resourceClient}, nil
 }
 
-func (c Client) Get(ctx context.Context, shootName string) (*gardenerv1beta1.Shoot, error) {
-	unstructuredShoot, err := c.ResourceClient.Get(ctx, shootName, metaV1.GetOptions{})
+// Reload rebuilds the client with the current Gardener kubeconfig
+func (c *Client) Reload(opts *options.Options) error {
+	resourceClient, err := gardenercommons.NewResourceClient(opts, GroupVersionResource())
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ResourceClient = resourceClient
+	return nil
+}
+
+func (c *Client) resourceClient() dynamic.ResourceInterface {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ResourceClient
+}
+
+func (c *Client) Get(ctx context.Context, shootName string) (*gardenerv1beta1.Shoot, error) {
+	unstructuredShoot, err := c.resourceClient().Get(ctx, shootName, metaV1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,69 @@
+package httpretry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned instead of sending a request while the upstream is considered down
+var ErrCircuitOpen = errors.New("circuit breaker is open as the upstream is down")
+
+// Breaker opens after Threshold consecutive failures and rejects the requests for Cooldown.
+// Afterwards it lets a request through and opens again right away if it fails.
+// All the methods are no-ops on a nil breaker.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+	}
+}
+
+// Allow checks if a request may be sent
+func (b *Breaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !time.Now().Before(b.openUntil)
+}
+
+// IsOpen checks if the requests are rejected at the moment
+func (b *Breaker) IsOpen() bool {
+	return !b.Allow()
+}
+
+// Success closes the breaker
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// Failure counts a failed request and opens the breaker when the threshold is reached
+func (b *Breaker) Failure() {
+	if b == nil || b.Threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.Threshold {
+		b.openUntil = time.Now().Add(b.Cooldown)
+	}
+}
@@ -0,0 +1,168 @@
+package httpretry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	retryAfterHeader = "Retry-After"
+	// maxBodyInError limits how much of a response body ends up in an error
+	maxBodyInError = 512
+)
+
+// StatusError is returned when the upstream responded with an unexpected HTTP status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected HTTP status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected HTTP status code: %d: %s", e.StatusCode, e.Body)
+}
+
+// IsRetryableStatus checks if a request which got the HTTP status code might succeed later
+func IsRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// IsRetryable checks if a failed request might succeed later, i.e. it failed with a network error, 429 or 5xx
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return IsRetryableStatus(statusErr.StatusCode)
+	}
+	return true
+}
+
+// Policy retries the requests which failed with a network error, 429 or 5xx with the backoff or as long as the
+// upstream asks for with Retry-After. Other 4xx responses fail right away. The Breaker is optional.
+type Policy struct {
+	Backoff       wait.Backoff
+	MaxRetryAfter time.Duration
+	Breaker       *Breaker
+	Logger        *logrus.Logger
+}
+
+// Do sends the request until it gets one of the expected HTTP status codes or the retries are exhausted.
+// The body of the returned response is already read and closed, hence it is returned separately.
+func (p Policy) Do(client *http.Client, req *http.Request, payload []byte, expectedStatusCodes ...int) (*http.Response, []byte, error) {
+	backoff := p.Backoff
+	steps := backoff.Steps
+	if steps < 1 {
+		steps = 1
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		resp, body, err := p.try(client, req, payload, expectedStatusCodes)
+		if err == nil {
+			p.Breaker.Success()
+			return resp, body, nil
+		}
+		lastErr = err
+
+		if errors.Is(err, ErrCircuitOpen) {
+			return nil, nil, err
+		}
+		if !IsRetryable(err) {
+			// The upstream is up but rejected the request
+			p.Breaker.Success()
+			return resp, body, err
+		}
+		p.Breaker.Failure()
+		if attempt >= steps {
+			break
+		}
+
+		delay := backoff.Step()
+		var statusErr StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+			if p.MaxRetryAfter > 0 && delay > p.MaxRetryAfter {
+				delay = p.MaxRetryAfter
+			}
+		}
+		if p.Logger != nil {
+			p.Logger.Warnf("will be retried after %v: %s %s: %v", delay, req.Method, req.URL.Host, err)
+		}
+		time.Sleep(delay)
+	}
+	return nil, nil, lastErr
+}
+
+func (p Policy) try(client *http.Client, req *http.Request, payload []byte, expectedStatusCodes []int) (*http.Response, []byte, error) {
+	if !p.Breaker.Allow() {
+		return nil, nil, ErrCircuitOpen
+	}
+	if payload != nil {
+		req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	respBody := resp.Body
+	defer func() {
+		_ = respBody.Close()
+	}()
+
+	body, err := ioutil.ReadAll(respBody)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read body")
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	for _, expected := range expectedStatusCodes {
+		if resp.StatusCode == expected {
+			return resp, body, nil
+		}
+	}
+	return resp, body, StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       truncate(strings.TrimSpace(string(body))),
+		RetryAfter: parseRetryAfter(resp.Header.Get(retryAfterHeader)),
+	}
+}
+
+// parseRetryAfter parses the Retry-After header which holds either seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+func truncate(body string) string {
+	if len(body) <= maxBodyInError {
+		return body
+	}
+	return fmt.Sprintf("%s...", body[:maxBodyInError])
+}
@@ -0,0 +1,121 @@
+package httpretry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestDo(t *testing.T) {
+	t.Run("retries 429 and 5xx until the expected status code", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		statusCodes := []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusCreated}
+		visited := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(statusCodes[visited])
+			visited++
+		}))
+		defer srv.Close()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+		g.Expect(err).Should(gomega.BeNil())
+		resp, _, err := newTestPolicy(3, nil).Do(srv.Client(), req, []byte("foo"), http.StatusCreated)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusCreated))
+		g.Expect(visited).To(gomega.Equal(3))
+	})
+
+	t.Run("fails fast on 4xx with the response body", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		visited := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			visited++
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte("invalid payload\n"))
+		}))
+		defer srv.Close()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+		g.Expect(err).Should(gomega.BeNil())
+		_, body, err := newTestPolicy(3, nil).Do(srv.Client(), req, []byte("foo"), http.StatusCreated)
+		g.Expect(err).Should(gomega.Equal(StatusError{StatusCode: http.StatusBadRequest, Body: "invalid payload"}))
+		g.Expect(err.Error()).To(gomega.Equal("unexpected HTTP status code: 400: invalid payload"))
+		g.Expect(IsRetryable(err)).To(gomega.BeFalse())
+		g.Expect(string(body)).To(gomega.Equal("invalid payload\n"))
+		g.Expect(visited).To(gomega.Equal(1))
+	})
+
+	t.Run("pauses the requests while the breaker is open", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+		visited := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			visited++
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		breaker := NewBreaker(2, time.Hour)
+		policy := newTestPolicy(5, breaker)
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		g.Expect(err).Should(gomega.BeNil())
+		_, _, err = policy.Do(srv.Client(), req, nil, http.StatusOK)
+		g.Expect(errors.Is(err, ErrCircuitOpen)).To(gomega.BeTrue())
+		g.Expect(visited).To(gomega.Equal(2))
+		g.Expect(breaker.IsOpen()).To(gomega.BeTrue())
+
+		_, _, err = policy.Do(srv.Client(), req, nil, http.StatusOK)
+		g.Expect(errors.Is(err, ErrCircuitOpen)).To(gomega.BeTrue())
+		g.Expect(visited).To(gomega.Equal(2))
+	})
+}
+
+func TestBreaker(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	breaker := NewBreaker(2, 10*time.Millisecond)
+
+	breaker.Failure()
+	g.Expect(breaker.Allow()).To(gomega.BeTrue())
+	breaker.Failure()
+	g.Expect(breaker.Allow()).To(gomega.BeFalse())
+
+	// Lets a request through after the cooldown and opens again right away if it fails
+	g.Eventually(breaker.Allow, time.Second).Should(gomega.BeTrue())
+	breaker.Failure()
+	g.Expect(breaker.Allow()).To(gomega.BeFalse())
+
+	g.Eventually(breaker.Allow, time.Second).Should(gomega.BeTrue())
+	breaker.Success()
+	breaker.Failure()
+	g.Expect(breaker.Allow()).To(gomega.BeTrue())
+
+	var nilBreaker *Breaker
+	nilBreaker.Failure()
+	g.Expect(nilBreaker.Allow()).To(gomega.BeTrue())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(parseRetryAfter("")).To(gomega.BeZero())
+	g.Expect(parseRetryAfter("120")).To(gomega.Equal(2 * time.Minute))
+	g.Expect(parseRetryAfter("foo")).To(gomega.BeZero())
+	g.Expect(parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))).To(gomega.BeZero())
+	g.Expect(parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))).To(gomega.BeNumerically(">", 59*time.Minute))
+}
+
+func newTestPolicy(steps int, breaker *Breaker) Policy {
+	return Policy{
+		Backoff: wait.Backoff{
+			Steps:    steps,
+			Duration: time.Millisecond,
+			Factor:   1.0,
+		},
+		Breaker: breaker,
+		Logger:  logrus.New(),
+	}
+}
@@ -3,40 +3,47 @@ package keb
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 
+	"github.com/kyma-incubator/metris/pkg/auth"
+	"github.com/kyma-incubator/metris/pkg/httpretry"
+	"github.com/kyma-project/control-plane/components/kyma-environment-broker/common/pagination"
 	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
 	"github.com/sirupsen/logrus"
-	"k8s.io/client-go/util/retry"
 )
 
 type Client struct {
 	HTTPClient *http.Client
 	Logger     *logrus.Logger
 	Config     *Config
+	Breaker    *httpretry.Breaker
 }
 
 const (
 	backOffJitter = 0.1
 	backOffFactor = 5.0
+	// maxRetryAfter caps how long KEB may ask to wait before retrying
+	maxRetryAfter = 2 * time.Minute
 )
 
-func NewClient(config *Config, logger *logrus.Logger) *Client {
-	kebHTTPClient := &http.Client{
-		Transport: http.DefaultTransport,
-		Timeout:   config.Timeout,
+func NewClient(config *Config, logger *logrus.Logger) (*Client, error) {
+	kebHTTPClient, err := auth.NewHTTPClient(config.Auth, config.Timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create HTTP client for KEB")
 	}
 	return &Client{
 		HTTPClient: kebHTTPClient,
 		Logger:     logger,
 		Config:     config,
-	}
+		Breaker:    httpretry.NewBreaker(config.BreakerThreshold, config.BreakerCooldown),
+	}, nil
 }
 
 func (c Client) NewRequest() (*http.Request, error) {
@@ -51,76 +58,138 @@ func (c Client) NewRequest() (*http.Request, error) {
 	return req, nil
 }
 
-func (c Client) GetAllRuntimes(req *http.Request) (*kebruntime.RuntimesPage, error) {
-	morePages := true
-	pageNum := 1
+// GetAllRuntimes returns the runtimes which pass the configured filter and the IDs of the listed runtimes which
+// are excluded by it. The first page tells how many pages there are and the remaining pages are fetched concurrently.
+func (c Client) GetAllRuntimes(req *http.Request) (*kebruntime.RuntimesPage, []string, error) {
+	firstPage, err := c.GetRuntimesPerPage(req, 1)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get runtimes from KEB")
+	}
+	c.Logger.Debugf("count: %d, page-num: %d, total-count: %d", firstPage.Count, 1, firstPage.TotalCount)
+
+	pages := []*kebruntime.RuntimesPage{firstPage}
+	if firstPage.Count > 0 && firstPage.Count < firstPage.TotalCount {
+		numOfPages := (firstPage.TotalCount + firstPage.Count - 1) / firstPage.Count
+		pages = append(pages, make([]*kebruntime.RuntimesPage, numOfPages-1)...)
+		if err := c.getRemainingPages(req, pages); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get runtimes from KEB")
+		}
+	}
+
 	recordsSeen := 0
 	finalRuntimesPage := new(kebruntime.RuntimesPage)
-	for morePages {
-		runtimesPage, err := c.GetRuntimesPerPage(req, pageNum)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get runtimes from KEB")
-		}
-		finalRuntimesPage.Data = append(finalRuntimesPage.Data, runtimesPage.Data...)
-		finalRuntimesPage.Count = len(finalRuntimesPage.Data)
+	var excludedRuntimeIDs []string
+	for _, runtimesPage := range pages {
 		recordsSeen += runtimesPage.Count
-		c.Logger.Debugf("count: %d, records-seen: %d, page-num: %d, total-count: %d", runtimesPage.Count, recordsSeen, pageNum, runtimesPage.TotalCount)
-		if recordsSeen >= runtimesPage.TotalCount {
-			morePages = false
-			continue
+		for _, runtime := range runtimesPage.Data {
+			if c.Config.Filter.Matches(runtime) {
+				finalRuntimesPage.Data = append(finalRuntimesPage.Data, runtime)
+			} else {
+				excludedRuntimeIDs = append(excludedRuntimeIDs, runtime.RuntimeID)
+			}
 		}
-		pageNum += 1
 	}
+	finalRuntimesPage.Count = len(finalRuntimesPage.Data)
 	finalRuntimesPage.TotalCount = recordsSeen
-	return finalRuntimesPage, nil
+	return finalRuntimesPage, excludedRuntimeIDs, nil
 }
 
-func (c Client) GetRuntimesPerPage(req *http.Request, pageNum int) (*kebruntime.RuntimesPage, error) {
-	c.Logger.Debugf("polling for runtimes with URL: %s", req.URL.String())
-	query := url.Values{
-		"page": []string{fmt.Sprintf("%d", pageNum)},
+// GetRuntime looks up a runtime by its ID regardless of the configured filter. It returns nil if KEB does not know
+// the runtime, so that a runtime which was deprovisioned can be told apart from one which is filtered out.
+func (c Client) GetRuntime(req *http.Request, runtimeID string) (*kebruntime.RuntimeDTO, error) {
+	query := url.Values{}
+	query.Set(kebruntime.RuntimeIDParam, runtimeID)
+	runtimesPage, err := c.getRuntimes(req, query)
+	if err != nil {
+		return nil, err
 	}
-	req.URL.RawQuery = query.Encode()
-	customBackoff := wait.Backoff{
-		Steps:    c.Config.RetryCount,
-		Duration: c.HTTPClient.Timeout,
-		Factor:   backOffFactor,
-		Jitter:   backOffJitter,
-	}
-	var resp *http.Response
-	var err error
-	err = retry.OnError(customBackoff, func(err error) bool {
-		if err != nil {
-			return true
+	for i := range runtimesPage.Data {
+		if runtimesPage.Data[i].RuntimeID == runtimeID {
+			return &runtimesPage.Data[i], nil
 		}
-		return false
-	}, func() (err error) {
-		resp, err = c.HTTPClient.Do(req)
+	}
+	return nil, nil
+}
+
+// getRemainingPages fills all but the first page with at most PageConcurrency requests in flight
+func (c Client) getRemainingPages(req *http.Request, pages []*kebruntime.RuntimesPage) error {
+	concurrency := c.Config.PageConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	pageNums := make(chan int)
+	errs := make([]error, len(pages))
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pageNum := range pageNums {
+				runtimesPage, err := c.GetRuntimesPerPage(req, pageNum)
+				if err != nil {
+					errs[pageNum-1] = err
+					continue
+				}
+				c.Logger.Debugf("count: %d, page-num: %d, total-count: %d", runtimesPage.Count, pageNum, runtimesPage.TotalCount)
+				pages[pageNum-1] = runtimesPage
+			}
+		}()
+	}
+	for pageNum := 2; pageNum <= len(pages); pageNum++ {
+		pageNums <- pageNum
+	}
+	close(pageNums)
+	wg.Wait()
+
+	for _, err := range errs {
 		if err != nil {
-			c.Logger.Warnf("will be retried: failed while getting runtimes from KEB: %v", err)
+			return err
 		}
-		return
-	})
-
-	if err != nil {
-		c.Logger.Errorf("failed to get runtimes from KEB: %v", err)
-		return nil, errors.Wrapf(err, "failed to get runtimes from KEB")
 	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		failedErr := fmt.Errorf("KEB returned status code: %d", resp.StatusCode)
-		c.Logger.Errorf("%v", failedErr)
-		return nil, failedErr
+func (c Client) GetRuntimesPerPage(req *http.Request, pageNum int) (*kebruntime.RuntimesPage, error) {
+	query := c.Config.Filter.QueryParams()
+	query.Set(pagination.PageParam, fmt.Sprintf("%d", pageNum))
+	if c.Config.PageSize > 0 {
+		query.Set(pagination.PageSizeParam, fmt.Sprintf("%d", c.Config.PageSize))
 	}
+	return c.getRuntimes(req, query)
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// getRuntimes gets the runtimes from KEB which match the query
+func (c Client) getRuntimes(req *http.Request, query url.Values) (*kebruntime.RuntimesPage, error) {
+	// The request is shared by the concurrent page requests hence every page gets its own copy
+	req = req.Clone(req.Context())
+	req.URL.RawQuery = query.Encode()
+	c.Logger.Debugf("polling for runtimes with URL: %s", req.URL.String())
+	policy := httpretry.Policy{
+		Backoff: wait.Backoff{
+			Steps:    c.Config.RetryCount,
+			Duration: c.HTTPClient.Timeout,
+			Factor:   backOffFactor,
+			Jitter:   backOffJitter,
+		},
+		MaxRetryAfter: maxRetryAfter,
+		Breaker:       c.Breaker,
+		Logger:        c.Logger,
+	}
+	_, body, err := policy.Do(c.HTTPClient, req, nil, http.StatusOK)
 	if err != nil {
-		c.Logger.Errorf("failed to read body: %v", err)
-		return nil, err
+		var statusErr httpretry.StatusError
+		if errors.As(err, &statusErr) {
+			failedErr := fmt.Errorf("KEB returned status code: %d", statusErr.StatusCode)
+			if statusErr.Body != "" {
+				failedErr = fmt.Errorf("KEB returned status code: %d: %s", statusErr.StatusCode, statusErr.Body)
+			}
+			c.Logger.Errorf("%v", failedErr)
+			return nil, failedErr
+		}
+		c.Logger.Errorf("failed to get runtimes from KEB: %v", err)
+		return nil, errors.Wrapf(err, "failed to get runtimes from KEB")
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+
 	runtimesPage := new(kebruntime.RuntimesPage)
 	if err := json.Unmarshal(body, runtimesPage); err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal runtimes response")
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -97,7 +99,7 @@ func TestGetAllRuntimes(t *testing.T) {
 		req, err := kebClient.NewRequest()
 		g.Expect(err).Should(gomega.BeNil())
 
-		gotRuntimes, err := kebClient.GetAllRuntimes(req)
+		gotRuntimes, _, err := kebClient.GetAllRuntimes(req)
 		g.Expect(err).Should(gomega.BeNil())
 		g.Expect(*gotRuntimes).To(gomega.Equal(*expectedRuntimes))
 		g.Expect(gotRuntimes.TotalCount).To(gomega.Equal(expectedRuntimes.TotalCount))
@@ -107,9 +109,9 @@ func TestGetAllRuntimes(t *testing.T) {
 		config.URL = fmt.Sprintf("%s/nopaging", kebClient.Config.URL)
 		req, err = kebClient.NewRequest()
 		g.Expect(err).Should(gomega.BeNil())
-		_, err = kebClient.GetAllRuntimes(req)
+		_, _, err = kebClient.GetAllRuntimes(req)
 		g.Expect(err).ShouldNot(gomega.BeNil())
-		g.Expect(err.Error()).To(gomega.Equal("failed to get runtimes from KEB: KEB returned status code: 404"))
+		g.Expect(err.Error()).To(gomega.Equal("failed to get runtimes from KEB: KEB returned status code: 404: 404 page not found"))
 	})
 
 	t.Run("when all runtimes are returned in 1 page", func(t *testing.T) {
@@ -160,10 +162,133 @@ func TestGetAllRuntimes(t *testing.T) {
 		// Testing response which contains all the records
 		req, err = kebClient.NewRequest()
 		g.Expect(err).Should(gomega.BeNil())
-		gotRuntimes, err := kebClient.GetAllRuntimes(req)
+		gotRuntimes, _, err := kebClient.GetAllRuntimes(req)
 		g.Expect(err).Should(gomega.BeNil())
 		g.Expect(*gotRuntimes).To(gomega.Equal(*expectedRuntimes))
 		g.Expect(gotRuntimes.TotalCount).To(gomega.Equal(expectedRuntimes.TotalCount))
 		g.Expect(len(gotRuntimes.Data)).To(gomega.Equal(4))
 	})
 }
+
+func TestGetAllRuntimesWithFilter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Every page has one runtime and the runtimes of odd pages belong to an excluded plan
+	const numOfPages = 7
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	getRuntimesHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		g.Expect(query[runtime.PlanParam]).To(gomega.Equal([]string{"azure", "trial"}))
+		g.Expect(query.Get(runtime.RegionParam)).To(gomega.Equal("westeurope"))
+		g.Expect(query[StateParam]).To(gomega.Equal([]string{"succeeded", "suspended"}))
+		g.Expect(query.Get("page_size")).To(gomega.Equal("1"))
+		pageNum, err := strconv.Atoi(query.Get("page"))
+		g.Expect(err).Should(gomega.BeNil())
+
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		kebRuntime := metristesting.NewRuntimesDTO(fmt.Sprintf("subaccount-%d", pageNum), fmt.Sprintf("shoot-%d", pageNum))
+		kebRuntime.ProviderRegion = "westeurope"
+		kebRuntime.ServicePlanName = "azure"
+		if pageNum%2 == 1 {
+			kebRuntime.ServicePlanName = "trial"
+		}
+		err = json.NewEncoder(rw).Encode(runtime.RuntimesPage{
+			Data:       []runtime.RuntimeDTO{kebRuntime},
+			Count:      1,
+			TotalCount: numOfPages,
+		})
+		g.Expect(err).Should(gomega.BeNil())
+	})
+	srv := metristesting.StartTestServer(expectedPathPrefix, getRuntimesHandler, g)
+	defer srv.Close()
+
+	kebClient := &Client{
+		HTTPClient: http.DefaultClient,
+		Logger:     &logrus.Logger{},
+		Config: &Config{
+			URL:             fmt.Sprintf("%s%s", srv.URL, expectedPathPrefix),
+			RetryCount:      1,
+			PageSize:        1,
+			PageConcurrency: 2,
+			Filter: Filter{
+				IncludePlans:   []string{"azure", "trial"},
+				ExcludePlans:   []string{"trial"},
+				IncludeRegions: []string{"westeurope"},
+				States:         []string{"succeeded", "suspended"},
+			},
+		},
+	}
+	req, err := kebClient.NewRequest()
+	g.Expect(err).Should(gomega.BeNil())
+
+	gotRuntimes, excludedRuntimeIDs, err := kebClient.GetAllRuntimes(req)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotRuntimes.TotalCount).To(gomega.Equal(numOfPages))
+	// The excluded runtimes are reported so that they stop being tracked
+	g.Expect(excludedRuntimeIDs).To(gomega.Equal([]string{
+		metristesting.NewRuntimeID("subaccount-1"),
+		metristesting.NewRuntimeID("subaccount-3"),
+		metristesting.NewRuntimeID("subaccount-5"),
+		metristesting.NewRuntimeID("subaccount-7"),
+	}))
+	var gotSubAccountIDs []string
+	for _, kebRuntime := range gotRuntimes.Data {
+		gotSubAccountIDs = append(gotSubAccountIDs, kebRuntime.SubAccountID)
+	}
+	// The order of the pages is kept
+	g.Expect(gotSubAccountIDs).To(gomega.Equal([]string{"subaccount-2", "subaccount-4", "subaccount-6"}))
+	g.Expect(gotRuntimes.Count).To(gomega.Equal(3))
+	g.Expect(maxInFlight).To(gomega.BeNumerically("<=", 2))
+}
+
+func TestGetRuntime(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	knownRuntime := metristesting.NewRuntimesDTO("subaccount", "shoot")
+	getRuntimesHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// The runtime is looked up without the filter
+		query := req.URL.Query()
+		g.Expect(query).To(gomega.HaveLen(1))
+		page := runtime.RuntimesPage{}
+		if query.Get(runtime.RuntimeIDParam) == knownRuntime.RuntimeID {
+			page.Data = []runtime.RuntimeDTO{knownRuntime}
+			page.Count = 1
+			page.TotalCount = 1
+		}
+		err := json.NewEncoder(rw).Encode(page)
+		g.Expect(err).Should(gomega.BeNil())
+	})
+	srv := metristesting.StartTestServer(expectedPathPrefix, getRuntimesHandler, g)
+	defer srv.Close()
+
+	kebClient := &Client{
+		HTTPClient: http.DefaultClient,
+		Logger:     &logrus.Logger{},
+		Config: &Config{
+			URL:        fmt.Sprintf("%s%s", srv.URL, expectedPathPrefix),
+			RetryCount: 1,
+			Filter:     Filter{IncludePlans: []string{"azure"}, States: []string{"succeeded"}},
+		},
+	}
+	req, err := kebClient.NewRequest()
+	g.Expect(err).Should(gomega.BeNil())
+
+	gotRuntime, err := kebClient.GetRuntime(req, knownRuntime.RuntimeID)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(*gotRuntime).To(gomega.Equal(knownRuntime))
+
+	gotRuntime, err = kebClient.GetRuntime(req, "unknown")
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotRuntime).To(gomega.BeNil())
+}
@@ -1,10 +1,20 @@
 package keb
 
-import "time"
+import (
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/auth"
+)
 
 type Config struct {
 	URL              string        `envconfig:"KEB_URL" required:"true"`
 	Timeout          time.Duration `envconfig:"KEB_TIMEOUT" default:"30s"`
 	RetryCount       int           `envconfig:"KEB_RETRY_COUNT" default:"5"`
 	PollWaitDuration time.Duration `envconfig:"KEB_POLL_WAIT_DURATION" default:"10m"`
+	BreakerThreshold int           `envconfig:"KEB_CIRCUIT_BREAKER_THRESHOLD" default:"5"`
+	BreakerCooldown  time.Duration `envconfig:"KEB_CIRCUIT_BREAKER_COOLDOWN" default:"1m"`
+	PageSize         int           `envconfig:"KEB_PAGE_SIZE"`
+	PageConcurrency  int           `envconfig:"KEB_PAGE_CONCURRENCY" default:"4"`
+	Filter           Filter        `envconfig:"KEB_FILTER"`
+	Auth             auth.Config   `envconfig:"KEB_AUTH"`
 }
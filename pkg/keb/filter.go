@@ -0,0 +1,64 @@
+package keb
+
+import (
+	"net/url"
+
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+)
+
+// StateParam is the KEB query parameter which selects the runtimes by their state
+const StateParam = "state"
+
+// Filter restricts the runtimes which are tracked by metris. Empty include lists match everything.
+type Filter struct {
+	// States are only passed to KEB, as the state of a runtime is not part of the listing
+	States                []string `envconfig:"STATES"`
+	IncludePlans          []string `envconfig:"INCLUDE_PLANS"`
+	ExcludePlans          []string `envconfig:"EXCLUDE_PLANS"`
+	IncludeGlobalAccounts []string `envconfig:"INCLUDE_GLOBAL_ACCOUNTS"`
+	ExcludeGlobalAccounts []string `envconfig:"EXCLUDE_GLOBAL_ACCOUNTS"`
+	IncludeRegions        []string `envconfig:"INCLUDE_REGIONS"`
+	ExcludeRegions        []string `envconfig:"EXCLUDE_REGIONS"`
+}
+
+// QueryParams returns the include lists as KEB query parameters so that KEB does the filtering.
+// KEB does not support excluding, hence the exclude lists are only applied by Matches.
+func (f Filter) QueryParams() url.Values {
+	query := url.Values{}
+	for _, plan := range f.IncludePlans {
+		query.Add(kebruntime.PlanParam, plan)
+	}
+	for _, globalAccount := range f.IncludeGlobalAccounts {
+		query.Add(kebruntime.GlobalAccountIDParam, globalAccount)
+	}
+	for _, region := range f.IncludeRegions {
+		query.Add(kebruntime.RegionParam, region)
+	}
+	for _, state := range f.States {
+		query.Add(StateParam, state)
+	}
+	return query
+}
+
+// Matches checks if the runtime passes the include and exclude lists
+func (f Filter) Matches(runtime kebruntime.RuntimeDTO) bool {
+	return isIncluded(f.IncludePlans, f.ExcludePlans, runtime.ServicePlanName) &&
+		isIncluded(f.IncludeGlobalAccounts, f.ExcludeGlobalAccounts, runtime.GlobalAccountID) &&
+		isIncluded(f.IncludeRegions, f.ExcludeRegions, runtime.ProviderRegion)
+}
+
+func isIncluded(includes, excludes []string, value string) bool {
+	if len(includes) > 0 && !contains(includes, value) {
+		return false
+	}
+	return !contains(excludes, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,358 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventFileSuffix = ".json"
+	tmpFileSuffix   = ".tmp"
+	eventFileMode   = 0600
+	dirMode         = 0700
+)
+
+var (
+	backlogGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "metris",
+		Subsystem: "outbox",
+		Name:      "backlog_events",
+		Help:      "The number of events in the outbox which are not delivered to EDP yet.",
+	})
+	droppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "metris",
+		Subsystem: "outbox",
+		Name:      "dropped_events_total",
+		Help:      "The number of undelivered events which were dropped as the outbox was full or EDP rejected them.",
+	})
+	replayedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "metris",
+		Subsystem: "outbox",
+		Name:      "replayed_events_total",
+		Help:      "The number of events which were delivered to EDP by the replayer.",
+	})
+)
+
+// ErrRejected tells the replayer to drop an event which will never be accepted by EDP
+var ErrRejected = errors.New("event was rejected")
+
+// Event is an event stream for a tenant which is not delivered to EDP yet
+type Event struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tenant"`
+	// DataStream is the EDP datastream of the event, the consumption metrics datastream if empty
+	DataStream string          `json:"data_stream,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+	// DeliveredVersions are the datastream versions which already accepted the event. They are skipped on replay.
+	DeliveredVersions []string `json:"delivered_versions,omitempty"`
+}
+
+// Outbox persists every event in a directory until EDP accepted it, so that no event is lost when EDP is down
+// or metris restarts. The events which could not be delivered by the workers are replayed in timestamp order.
+// All the methods are no-ops on a nil outbox.
+type Outbox struct {
+	Dir            string
+	MaxEvents      int
+	ReplayInterval time.Duration
+	Logger         *logrus.Logger
+
+	mu sync.Mutex
+	// pending maps the IDs of the undelivered events to their tenants
+	pending map[string]string
+	// inFlight holds the IDs of the events which are being sent by a worker
+	inFlight map[string]bool
+	seq      uint64
+}
+
+// New creates an outbox in dir and loads the events which were left undelivered by a previous run
+func New(dir string, maxEvents int, replayInterval time.Duration, logger *logrus.Logger) (*Outbox, error) {
+	if replayInterval <= 0 {
+		return nil, fmt.Errorf("replay interval must be positive: %v", replayInterval)
+	}
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, errors.Wrapf(err, "failed to create outbox dir")
+	}
+	o := &Outbox{
+		Dir:            dir,
+		MaxEvents:      maxEvents,
+		ReplayInterval: replayInterval,
+		Logger:         logger,
+		pending:        make(map[string]string),
+		inFlight:       make(map[string]bool),
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read outbox dir")
+	}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, tmpFileSuffix) {
+			// Leftover of an interrupted write
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if !strings.HasSuffix(name, eventFileSuffix) {
+			continue
+		}
+		event, err := o.read(strings.TrimSuffix(name, eventFileSuffix))
+		if err != nil {
+			logger.Warnf("skipping unreadable event %s in outbox: %v", name, err)
+			continue
+		}
+		o.pending[event.ID] = event.Tenant
+	}
+	backlogGauge.Set(float64(len(o.pending)))
+	logger.Infof("loaded %d undelivered events from outbox: %s", len(o.pending), dir)
+	return o, nil
+}
+
+// Put persists an event and marks it as in-flight until it is deleted or released
+func (o *Outbox) Put(tenant string, payload []byte) (string, error) {
+	return o.PutForDataStream("", tenant, payload)
+}
+
+// PutForDataStream persists an event for another EDP datastream than the consumption metrics one
+func (o *Outbox) PutForDataStream(dataStream, tenant string, payload []byte) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now().UTC()
+	o.seq++
+	event := Event{
+		ID:         fmt.Sprintf("%020d-%06d", now.UnixNano(), o.seq%1000000),
+		Tenant:     tenant,
+		DataStream: dataStream,
+		Payload:    payload,
+		CreatedAt:  now,
+	}
+	o.enforceMaxEvents()
+	if err := o.write(&event); err != nil {
+		return "", err
+	}
+	o.pending[event.ID] = tenant
+	o.inFlight[event.ID] = true
+	backlogGauge.Set(float64(len(o.pending)))
+	return event.ID, nil
+}
+
+// MarkDelivered records that a datastream version accepted an event, so that it is not sent to it again
+// when the event is replayed
+func (o *Outbox) MarkDelivered(id, version string) error {
+	if o == nil || id == "" {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.pending[id]; !ok {
+		return nil
+	}
+	event, err := o.read(id)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read event from outbox")
+	}
+	for _, delivered := range event.DeliveredVersions {
+		if delivered == version {
+			return nil
+		}
+	}
+	event.DeliveredVersions = append(event.DeliveredVersions, version)
+	return o.write(event)
+}
+
+// Delete removes a delivered event from the outbox
+func (o *Outbox) Delete(id string) error {
+	if o == nil || id == "" {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.delete(id)
+}
+
+// Release hands an event which could not be delivered by a worker over to the replayer
+func (o *Outbox) Release(id string) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.inFlight, id)
+}
+
+// HasOlderPending checks if an older event of the tenant is still undelivered.
+// The events of a tenant have to be delivered in timestamp order.
+func (o *Outbox) HasOlderPending(tenant, id string) bool {
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for pendingID, pendingTenant := range o.pending {
+		if pendingTenant == tenant && pendingID < id {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of undelivered events
+func (o *Outbox) Len() int {
+	if o == nil {
+		return 0
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Replay delivers the undelivered events in timestamp order. When an event of a tenant fails,
+// the newer events of the same tenant are kept back till the next replay.
+func (o *Outbox) Replay(send func(event Event) error) {
+	if o == nil {
+		return
+	}
+	failedTenants := make(map[string]bool)
+	for _, id := range o.replayableIDs() {
+		event, err := o.read(id)
+		if err != nil {
+			o.Logger.Errorf("failed to read event %s from outbox: %v", id, err)
+			continue
+		}
+		if failedTenants[event.Tenant] {
+			continue
+		}
+		if err := send(*event); errors.Is(err, ErrRejected) {
+			o.Logger.Errorf("dropping event %s for tenant: %s as it was rejected: %v", id, event.Tenant, err)
+			if err := o.Delete(id); err != nil {
+				o.Logger.Errorf("failed to delete rejected event %s from outbox: %v", id, err)
+			}
+			droppedCounter.Inc()
+			continue
+		} else if err != nil {
+			o.Logger.Warnf("failed to replay event %s for tenant: %s: %v", id, event.Tenant, err)
+			failedTenants[event.Tenant] = true
+			continue
+		}
+		if err := o.Delete(id); err != nil {
+			o.Logger.Errorf("failed to delete replayed event %s from outbox: %v", id, err)
+		}
+		replayedCounter.Inc()
+		o.Logger.Debugf("replayed event %s for tenant: %s", id, event.Tenant)
+	}
+}
+
+// Run replays the undelivered events every ReplayInterval until the context is done
+func (o *Outbox) Run(ctx context.Context, send func(event Event) error) {
+	if o == nil {
+		return
+	}
+	ticker := time.NewTicker(o.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.Replay(send)
+		}
+	}
+}
+
+// replayableIDs returns the IDs of the undelivered events which are not in-flight in timestamp order
+func (o *Outbox) replayableIDs() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ids := make([]string, 0, len(o.pending))
+	for id := range o.pending {
+		if !o.inFlight[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// enforceMaxEvents drops the oldest events to make room for a new one
+func (o *Outbox) enforceMaxEvents() {
+	if o.MaxEvents <= 0 || len(o.pending) < o.MaxEvents {
+		return
+	}
+	ids := make([]string, 0, len(o.pending))
+	for id := range o.pending {
+		if !o.inFlight[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if len(o.pending) < o.MaxEvents {
+			return
+		}
+		if err := o.delete(id); err != nil {
+			o.Logger.Errorf("failed to drop event %s from full outbox: %v", id, err)
+			continue
+		}
+		droppedCounter.Inc()
+		o.Logger.Warnf("dropped undelivered event %s for tenant as the outbox is full", id)
+	}
+}
+
+func (o *Outbox) delete(id string) error {
+	if err := os.Remove(o.path(id)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete event from outbox")
+	}
+	delete(o.pending, id)
+	delete(o.inFlight, id)
+	backlogGauge.Set(float64(len(o.pending)))
+	return nil
+}
+
+// write persists an event atomically, so that an interrupted write does not leave a partial event behind
+func (o *Outbox) write(event *Event) error {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "failed to json.Marshal event")
+	}
+	tmpPath := o.path(event.ID) + tmpFileSuffix
+	if err := ioutil.WriteFile(tmpPath, eventBytes, eventFileMode); err != nil {
+		return errors.Wrapf(err, "failed to write event to outbox")
+	}
+	if err := os.Rename(tmpPath, o.path(event.ID)); err != nil {
+		return errors.Wrapf(err, "failed to write event to outbox")
+	}
+	return nil
+}
+
+func (o *Outbox) read(id string) (*Event, error) {
+	eventBytes, err := ioutil.ReadFile(o.path(id))
+	if err != nil {
+		return nil, err
+	}
+	event := new(Event)
+	if err := json.Unmarshal(eventBytes, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.Dir, fmt.Sprintf("%s%s", id, eventFileSuffix))
+}
@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPutAndDelete(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir := newTempDir(t)
+	o, err := New(dir, 10, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	fooID, err := o.Put("foo", []byte(`{"foo":1}`))
+	g.Expect(err).Should(gomega.BeNil())
+	barID, err := o.Put("bar", []byte(`{"bar":1}`))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(o.Len()).To(gomega.Equal(2))
+
+	err = o.Delete(fooID)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(o.Len()).To(gomega.Equal(1))
+
+	// The undelivered events survive a restart
+	reloaded, err := New(dir, 10, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(reloaded.Len()).To(gomega.Equal(1))
+	event, err := reloaded.read(barID)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(event.Tenant).To(gomega.Equal("bar"))
+	g.Expect(string(event.Payload)).To(gomega.Equal(`{"bar":1}`))
+}
+
+func TestMarkDelivered(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir := newTempDir(t)
+	o, err := New(dir, 10, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	id, err := o.Put("foo", []byte(`{"foo":1}`))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(o.MarkDelivered(id, "1")).Should(gomega.Succeed())
+	g.Expect(o.MarkDelivered(id, "1")).Should(gomega.Succeed())
+
+	// The delivered versions survive a restart
+	reloaded, err := New(dir, 10, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+	event, err := reloaded.read(id)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(event.DeliveredVersions).To(gomega.Equal([]string{"1"}))
+	g.Expect(string(event.Payload)).To(gomega.Equal(`{"foo":1}`))
+
+	// A deleted event is not brought back
+	g.Expect(o.Delete(id)).Should(gomega.Succeed())
+	g.Expect(o.MarkDelivered(id, "2")).Should(gomega.Succeed())
+	_, err = o.read(id)
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+}
+
+func TestReplay(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	o, err := New(newTempDir(t), 10, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := o.Put("foo", []byte(fmt.Sprintf(`{"foo":%d}`, i)))
+		g.Expect(err).Should(gomega.BeNil())
+		ids = append(ids, id)
+	}
+	inFlightID, err := o.Put("bar", []byte(`{"bar":1}`))
+	g.Expect(err).Should(gomega.BeNil())
+	for _, id := range ids {
+		o.Release(id)
+	}
+	g.Expect(o.HasOlderPending("foo", ids[2])).To(gomega.BeTrue())
+	g.Expect(o.HasOlderPending("foo", ids[0])).To(gomega.BeFalse())
+
+	// A failed event holds back the newer events of the same tenant
+	var sent []string
+	o.Replay(func(event Event) error {
+		sent = append(sent, string(event.Payload))
+		if len(sent) == 2 {
+			return fmt.Errorf("EDP is down")
+		}
+		return nil
+	})
+	g.Expect(sent).To(gomega.Equal([]string{`{"foo":0}`, `{"foo":1}`}))
+	g.Expect(o.Len()).To(gomega.Equal(3))
+
+	// The in-flight events are left to the workers
+	sent = nil
+	o.Replay(func(event Event) error {
+		sent = append(sent, string(event.Payload))
+		return nil
+	})
+	g.Expect(sent).To(gomega.Equal([]string{`{"foo":1}`, `{"foo":2}`}))
+	g.Expect(o.Len()).To(gomega.Equal(1))
+	g.Expect(o.HasOlderPending("bar", inFlightID)).To(gomega.BeFalse())
+}
+
+func TestReplayDropsRejectedEvents(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	o, err := New(newTempDir(t), 10, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	for i := 0; i < 2; i++ {
+		id, err := o.Put("foo", []byte(fmt.Sprintf(`{"foo":%d}`, i)))
+		g.Expect(err).Should(gomega.BeNil())
+		o.Release(id)
+	}
+
+	// A rejected event does not hold back the newer events
+	var sent []string
+	o.Replay(func(event Event) error {
+		sent = append(sent, string(event.Payload))
+		if len(sent) == 1 {
+			return ErrRejected
+		}
+		return nil
+	})
+	g.Expect(sent).To(gomega.Equal([]string{`{"foo":0}`, `{"foo":1}`}))
+	g.Expect(o.Len()).To(gomega.Equal(0))
+}
+
+func TestMaxEvents(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	o, err := New(newTempDir(t), 2, time.Minute, logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	for i := 0; i < 3; i++ {
+		id, err := o.Put("foo", []byte(fmt.Sprintf(`{"foo":%d}`, i)))
+		g.Expect(err).Should(gomega.BeNil())
+		o.Release(id)
+	}
+	g.Expect(o.Len()).To(gomega.Equal(2))
+
+	// The oldest event was dropped
+	var sent []string
+	o.Replay(func(event Event) error {
+		sent = append(sent, string(event.Payload))
+		return nil
+	})
+	g.Expect(sent).To(gomega.Equal([]string{`{"foo":1}`, `{"foo":2}`}))
+	g.Expect(o.Len()).To(gomega.Equal(0))
+}
+
+func TestInvalidReplayInterval(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	_, err := New(newTempDir(t), 10, 0, logrus.New())
+	g.Expect(err).ShouldNot(gomega.BeNil())
+}
+
+func TestNilOutbox(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var o *Outbox
+
+	id, err := o.Put("foo", []byte(`{}`))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(o.HasOlderPending("foo", id)).To(gomega.BeFalse())
+	g.Expect(o.Delete(id)).Should(gomega.BeNil())
+	g.Expect(o.Len()).To(gomega.Equal(0))
+}
+
+func newTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	return dir
+}
@@ -36,7 +36,10 @@ type Input struct {
 	shoot    *gardencorev1beta1.Shoot
 	nodeList *corev1.NodeList
 	pvcList  *corev1.PersistentVolumeClaimList
+	pvList   *corev1.PersistentVolumeList
 	svcList  *corev1.ServiceList
+	// scrapeInterval aligns the timestamp of the metric
+	scrapeInterval time.Duration
 }
 
 type NodeInfo struct {
@@ -57,10 +60,15 @@ func (inp Input) Parse(providers *Providers) (*edp.ConsumptionMetrics, error) {
 	provisionedCPUs := 0
 	provisionedMemory := 0.0
 	providerType := inp.shoot.Spec.Provider.Type
-	vmTypes := make(map[string]int)
-
+	vmTypes := make(map[string]*edp.VMType)
+	// reportedGPUTypes are the VM types whose GPU type is the product reported by a node
+	reportedGPUTypes := make(map[string]bool)
+	provisionedGPUs := 0
+	extendedResources := make(map[string]int64)
+
+	workers := getWorkers(inp.shoot)
+	nodeVolumes := make(map[string]*edp.VolumeType)
 	nodeStorage := int64(0)
-	pvcStorage := int64(0)
 	volumeCount := 0
 	vnets := 0
 
@@ -75,34 +83,43 @@ func (inp Input) Parse(providers *Providers) (*edp.ConsumptionMetrics, error) {
 		}
 		provisionedCPUs += vmFeatures.CpuCores
 		provisionedMemory += vmFeatures.Memory
-		vmTypes[nodeType] += 1
+		if _, ok := vmTypes[nodeType]; !ok {
+			vmTypes[nodeType] = &edp.VMType{Name: nodeType}
+		}
+		vmTypes[nodeType].Count += 1
+
+		// Calculate GPUs and other extended resources
+		gpus, gpuType, isReported := getNodeGPUs(&node, vmFeatures)
+		if gpus > 0 {
+			vmTypes[nodeType].GPUs += gpus
+			// The product reported by a node is never overwritten by the spec table
+			if vmTypes[nodeType].GPUType == "" || (isReported && !reportedGPUTypes[nodeType]) {
+				vmTypes[nodeType].GPUType = gpuType
+				reportedGPUTypes[nodeType] = isReported
+			}
+			provisionedGPUs += gpus
+		}
+		addExtendedResources(extendedResources, &node)
 
 		// Calculate node storage
-		nodeStorage += vmFeatures.Storage
+		volumeType, volumeSize := getNodeVolume(workers[node.Labels[workerPoolLabel]], vmFeatures.Storage)
+		nodeStorage += volumeSize
 		volumeCount += 1
+		if _, ok := nodeVolumes[volumeType]; !ok {
+			nodeVolumes[volumeType] = &edp.VolumeType{Type: volumeType}
+		}
+		nodeVolumes[volumeType].Count += 1
+		nodeVolumes[volumeType].SizeGbTotal += volumeSize
 
 	}
 
-	if inp.pvcList != nil {
-		// Calculate storage from PVCs
-		for _, pvc := range inp.pvcList.Items {
-			if pvc.Status.Phase == corev1.ClaimBound {
-				currPVC := getSizeInGB(pvc.Status.Capacity.Storage())
-				pvcStorage += currPVC
-				volumeCount += 1
-			}
-		}
-	}
+	// Calculate storage from PVCs and the retained PVs
+	storageClasses := getVolumeStorage(inp.pvcList, inp.pvList)
+	pvcStorage, pvcCount := getTotalStorage(storageClasses)
+	volumeCount += pvcCount
 
-	provisionedIPs := 0
-	if inp.svcList != nil {
-		// Calculate network related information
-		for _, svc := range inp.svcList.Items {
-			if svc.Spec.Type == "LoadBalancer" {
-				provisionedIPs += 1
-			}
-		}
-	}
+	// Calculate network related information
+	networking := getLoadBalancers(inp.svcList)
 
 	// Calculate vnets
 	if inp.shoot.Spec.Provider.InfrastructureConfig != nil {
@@ -120,11 +137,12 @@ func (inp Input) Parse(providers *Providers) (*edp.ConsumptionMetrics, error) {
 			if infraConfig.Networks.VNet.CIDR != nil {
 				vnets += 1
 			}
+			networking.NATGatewayIPs = getNATGatewayIPs(infraConfig)
 		default:
 			return nil, fmt.Errorf("provider: %s does not match in the system", inp.shoot.Spec.Provider.Type)
 		}
 	}
-	metric.Timestamp = getTimestampNow()
+	metric.Timestamp = getTimestampNow(inp.scrapeInterval)
 	metric.Compute.ProvisionedCpus = provisionedCPUs
 	metric.Compute.ProvisionedRAMGb = provisionedMemory
 
@@ -133,22 +151,26 @@ func (inp Input) Parse(providers *Providers) (*edp.ConsumptionMetrics, error) {
 	metric.Compute.ProvisionedVolumes.SizeGbRounded = getVolumeRoundedToFactor(totalActualStorage)
 	metric.Compute.ProvisionedVolumes.Count = volumeCount
 
-	metric.Networking.ProvisionedIPs = provisionedIPs
+	metric.Networking = networking
 	metric.Networking.ProvisionedVnets = vnets
 
-	for vmType, count := range vmTypes {
-		metric.Compute.VMTypes = append(metric.Compute.VMTypes, edp.VMType{
-			Name:  vmType,
-			Count: count,
-		})
+	metric.Compute.WorkerPools = getWorkerPools(inp.shoot, inp.nodeList)
+	metric.Compute.NodeVolumes = sortVolumeTypes(nodeVolumes)
+	metric.Compute.StorageClasses = storageClasses
+	metric.Compute.ProvisionedGPUs = provisionedGPUs
+	if len(extendedResources) > 0 {
+		metric.Compute.ExtendedResources = extendedResources
 	}
 
+	metric.Compute.VMTypes = sortVMTypes(vmTypes)
+
 	return metric, nil
 }
 
-// getTimestampNow returns the time now in the format of RFC3339
-func getTimestampNow() string {
-	return time.Now().Format(time.RFC3339)
+// getTimestampNow returns the start of the current interval in the format of RFC3339. All the snapshots which are
+// taken in an interval get the same timestamp, so that the resent ones can be recognized.
+func getTimestampNow(interval time.Duration) string {
+	return time.Now().UTC().Truncate(interval).Format(time.RFC3339)
 }
 
 func getVolumeRoundedToFactor(size int64) int64 {
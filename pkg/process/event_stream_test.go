@@ -3,6 +3,8 @@ package process
 import (
 	"testing"
 
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/kyma-incubator/metris/env"
@@ -50,10 +52,23 @@ func TestParse(t *testing.T) {
 						Count:         5,
 						SizeGbRounded: 448,
 					},
+					WorkerPools: []edp.WorkerPool{{
+						Name:        "cpu-worker-0",
+						MachineType: "Standard_D8_v3",
+						Nodes:       2,
+						Minimum:     2,
+						Maximum:     5,
+						Zones:       []string{"1", "2"},
+						System:      true,
+					}},
+					NodeVolumes:    []edp.VolumeType{{Type: "default", Count: 2, SizeGbTotal: 400}},
+					StorageClasses: []edp.StorageClass{{Count: 3, SizeGb: 35}},
 				},
 				Networking: edp.Networking{
-					ProvisionedVnets: 1,
-					ProvisionedIPs:   1,
+					ProvisionedVnets:      1,
+					ProvisionedIPs:        1,
+					PublicLoadBalancers:   1,
+					PublicLoadBalancerIPs: 1,
 				},
 			},
 		},
@@ -78,6 +93,16 @@ func TestParse(t *testing.T) {
 						Count:         3,
 						SizeGbRounded: 608,
 					},
+					WorkerPools: []edp.WorkerPool{{
+						Name:        "cpu-worker-0",
+						MachineType: "Standard_D8_v3",
+						Nodes:       3,
+						Minimum:     2,
+						Maximum:     5,
+						Zones:       []string{"1", "2"},
+						System:      true,
+					}},
+					NodeVolumes: []edp.VolumeType{{Type: "default", Count: 3, SizeGbTotal: 600}},
 				},
 				Networking: edp.Networking{
 					ProvisionedVnets: 1,
@@ -105,6 +130,16 @@ func TestParse(t *testing.T) {
 						Count:         3,
 						SizeGbRounded: 608,
 					},
+					WorkerPools: []edp.WorkerPool{{
+						Name:        "cpu-worker-0",
+						MachineType: "Standard_D8_v3",
+						Nodes:       3,
+						Minimum:     2,
+						Maximum:     5,
+						Zones:       []string{"1", "2"},
+						System:      true,
+					}},
+					NodeVolumes: []edp.VolumeType{{Type: "default", Count: 3, SizeGbTotal: 600}},
 				},
 				Networking: edp.Networking{
 					ProvisionedVnets: 1,
@@ -112,6 +147,103 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with Azure with 3 vms whose volume is configured by the worker",
+			input: Input{
+				shoot: metristesting.GetShoot("testShoot", metristesting.WithAzureProviderAndStandardD8V3VMs, func(shoot *gardencorev1beta1.Shoot) {
+					volumeType := "StandardSSD_LRS"
+					shoot.Spec.Provider.Workers[0].Volume = &gardencorev1beta1.Volume{Type: &volumeType, VolumeSize: "50Gi"}
+				}),
+				nodeList: metristesting.Get3NodesWithStandardD8v3VMType(),
+			},
+			providers: *providers,
+			expectedMetrics: edp.ConsumptionMetrics{
+				Compute: edp.Compute{
+					VMTypes: []edp.VMType{{
+						Name:  "standard_d8_v3",
+						Count: 3,
+					}},
+					ProvisionedCpus:  24,
+					ProvisionedRAMGb: 96,
+					ProvisionedVolumes: edp.ProvisionedVolumes{
+						SizeGbTotal:   150,
+						Count:         3,
+						SizeGbRounded: 160,
+					},
+					WorkerPools: []edp.WorkerPool{{
+						Name:         "cpu-worker-0",
+						MachineType:  "Standard_D8_v3",
+						Nodes:        3,
+						Minimum:      2,
+						Maximum:      5,
+						Zones:        []string{"1", "2"},
+						VolumeType:   "StandardSSD_LRS",
+						VolumeSizeGb: 50,
+						System:       true,
+					}},
+					NodeVolumes: []edp.VolumeType{{Type: "StandardSSD_LRS", Count: 3, SizeGbTotal: 150}},
+				},
+				Networking: edp.Networking{
+					ProvisionedVnets: 1,
+					ProvisionedIPs:   0,
+				},
+			},
+		},
+		{
+			name: "with Azure with GPU vms",
+			input: Input{
+				shoot: metristesting.GetShoot("testShoot", metristesting.WithAzureProviderAndStandardD8V3VMs, func(shoot *gardencorev1beta1.Shoot) {
+					shoot.Spec.Provider.Workers[0].Machine.Type = "Standard_NC6s_v3"
+				}),
+				nodeList: func() *corev1.NodeList {
+					// The GPUs of the second node are reported by the device plugin, the other ones fall back to the spec table
+					node1 := metristesting.GetNode("node1", "Standard_NC6s_v3")
+					node1.Labels["nvidia.com/gpu.product"] = "Tesla-V100-PCIE-16GB"
+					node1.Status.Capacity = corev1.ResourceList{
+						"cpu":              resource.MustParse("6"),
+						"nvidia.com/gpu":   resource.MustParse("1"),
+						"example.com/fpga": resource.MustParse("2"),
+					}
+					node2 := metristesting.GetNode("node2", "Standard_NC6s_v3")
+					node3 := metristesting.GetNode("node3", "Standard_NC6s_v3")
+					return &corev1.NodeList{Items: []corev1.Node{node2, node1, node3}}
+				}(),
+			},
+			providers: *providers,
+			expectedMetrics: edp.ConsumptionMetrics{
+				Compute: edp.Compute{
+					VMTypes: []edp.VMType{{
+						Name:  "standard_nc6s_v3",
+						Count: 3,
+						GPUs:  3,
+						// The product reported by the node wins over the spec table
+						GPUType: "Tesla-V100-PCIE-16GB",
+					}},
+					ProvisionedCpus:  18,
+					ProvisionedRAMGb: 336,
+					ProvisionedVolumes: edp.ProvisionedVolumes{
+						SizeGbTotal:   2208,
+						Count:         3,
+						SizeGbRounded: 2208,
+					},
+					WorkerPools: []edp.WorkerPool{{
+						Name:        "cpu-worker-0",
+						MachineType: "Standard_NC6s_v3",
+						Nodes:       3,
+						Minimum:     2,
+						Maximum:     5,
+						Zones:       []string{"1", "2"},
+						System:      true,
+					}},
+					NodeVolumes:       []edp.VolumeType{{Type: "default", Count: 3, SizeGbTotal: 2208}},
+					ProvisionedGPUs:   3,
+					ExtendedResources: map[string]int64{"nvidia.com/gpu": 1, "example.com/fpga": 2},
+				},
+				Networking: edp.Networking{
+					ProvisionedVnets: 1,
+				},
+			},
+		},
 		{
 			name: "with Azure and vm type missing from the list of vmtypes",
 			input: Input{
@@ -0,0 +1,259 @@
+package process
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kyma-incubator/metris/pkg/edp"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+	"github.com/pkg/errors"
+)
+
+const (
+	operationStateSucceeded = "succeeded"
+	lifecycleStateFileName  = "lifecycle.json"
+	lifecycleTmpFileSuffix  = ".tmp"
+	lifecycleStateFileMode  = 0600
+	lifecycleDirMode        = 0700
+)
+
+// runtimeState is the part of a runtime in KEB which is diffed to find its lifecycle transitions
+type runtimeState struct {
+	SubAccountID  string `json:"sub_account_id"`
+	InstanceID    string `json:"instance_id"`
+	ShootName     string `json:"shoot_name"`
+	Provisioned   bool   `json:"provisioned"`
+	Deprovisioned bool   `json:"deprovisioned"`
+	// The creation times of the latest operations which were turned into events
+	LastUpgradeAt      time.Time `json:"last_upgrade_at"`
+	LastSuspensionAt   time.Time `json:"last_suspension_at"`
+	LastUnsuspensionAt time.Time `json:"last_unsuspension_at"`
+}
+
+func newRuntimeState(runtime *kebruntime.RuntimeDTO) runtimeState {
+	state := runtimeState{
+		SubAccountID:  runtime.SubAccountID,
+		InstanceID:    runtime.InstanceID,
+		ShootName:     runtime.ShootName,
+		Provisioned:   runtime.Status.Provisioning != nil && runtime.Status.Provisioning.State == operationStateSucceeded,
+		Deprovisioned: runtime.Status.Deprovisioning != nil,
+	}
+	if upgrade := latestSucceededOperation(runtime.Status.UpgradingKyma); upgrade != nil {
+		state.LastUpgradeAt = upgrade.CreatedAt
+	}
+	if suspension := latestOperation(runtime.Status.Suspension); suspension != nil {
+		state.LastSuspensionAt = suspension.CreatedAt
+	}
+	if unsuspension := latestOperation(runtime.Status.Unsuspension); unsuspension != nil {
+		state.LastUnsuspensionAt = unsuspension.CreatedAt
+	}
+	return state
+}
+
+// latestSucceededOperation returns the latest operation which succeeded
+func latestSucceededOperation(operations kebruntime.OperationsData) *kebruntime.Operation {
+	var latest *kebruntime.Operation
+	for i := range operations.Data {
+		operation := &operations.Data[i]
+		if operation.State != operationStateSucceeded {
+			continue
+		}
+		if latest == nil || operation.CreatedAt.After(latest.CreatedAt) {
+			latest = operation
+		}
+	}
+	return latest
+}
+
+// operationsSince returns the operations which were created after since. Only the succeeded operations are
+// returned if succeededOnly is set, otherwise all but the failed ones.
+func operationsSince(operations kebruntime.OperationsData, since time.Time, succeededOnly bool) []kebruntime.Operation {
+	var created []kebruntime.Operation
+	for _, operation := range operations.Data {
+		if (succeededOnly && operation.State != operationStateSucceeded) || operation.State == operationStateFailed {
+			continue
+		}
+		if operation.CreatedAt.After(since) {
+			created = append(created, operation)
+		}
+	}
+	return created
+}
+
+// lifecycleEvents returns the transitions of a runtime since its previous state. They are derived from the KEB
+// operations which were created since, so that every upgrade, suspension and unsuspension is reported at the time
+// it happened even if multiple of them happened between two polls. A nil previous state means the runtime showed up
+// in KEB since the last poll.
+func lifecycleEvents(previous *runtimeState, runtime *kebruntime.RuntimeDTO, now time.Time) []edp.LifecycleEvent {
+	current := newRuntimeState(runtime)
+	newEvent := func(eventType string, operation *kebruntime.Operation) edp.LifecycleEvent {
+		event := edp.LifecycleEvent{
+			Type:       eventType,
+			RuntimeID:  runtime.RuntimeID,
+			InstanceID: runtime.InstanceID,
+			ShootName:  runtime.ShootName,
+			Timestamp:  now.Format(time.RFC3339),
+		}
+		if operation != nil {
+			event.OperationID = operation.OperationID
+			event.Timestamp = operation.CreatedAt.Format(time.RFC3339)
+		}
+		return event
+	}
+	since := runtimeState{}
+	if previous != nil {
+		since = *previous
+	}
+
+	var events []edp.LifecycleEvent
+	if current.Provisioned && !since.Provisioned {
+		events = append(events, newEvent(edp.LifecycleProvisioned, runtime.Status.Provisioning))
+	}
+	if since.ShootName != "" && current.ShootName != "" && since.ShootName != current.ShootName {
+		event := newEvent(edp.LifecycleShootRenamed, nil)
+		event.PreviousShootName = since.ShootName
+		events = append(events, event)
+	}
+
+	type transition struct {
+		eventType string
+		operation kebruntime.Operation
+	}
+	var transitions []transition
+	for _, operation := range operationsSince(runtime.Status.UpgradingKyma, since.LastUpgradeAt, true) {
+		transitions = append(transitions, transition{eventType: edp.LifecycleUpgraded, operation: operation})
+	}
+	for _, operation := range operationsSince(runtime.Status.Suspension, since.LastSuspensionAt, false) {
+		transitions = append(transitions, transition{eventType: edp.LifecycleSuspended, operation: operation})
+	}
+	for _, operation := range operationsSince(runtime.Status.Unsuspension, since.LastUnsuspensionAt, false) {
+		transitions = append(transitions, transition{eventType: edp.LifecycleUnsuspended, operation: operation})
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].operation.CreatedAt.Before(transitions[j].operation.CreatedAt)
+	})
+	for i := range transitions {
+		events = append(events, newEvent(transitions[i].eventType, &transitions[i].operation))
+	}
+
+	if current.Deprovisioned && !since.Deprovisioned {
+		events = append(events, newEvent(edp.LifecycleDeprovisioned, runtime.Status.Deprovisioning))
+	}
+	return events
+}
+
+// trackLifecycle diffs the runtime against its state from the previous poll and sends its lifecycle transitions.
+// The states are only recorded during the first poll without a saved state, as the transitions which happened
+// before Metris ever started are unknown.
+func (p *Process) trackLifecycle(runtime *kebruntime.RuntimeDTO, isFirstPoll bool) {
+	previous, isKnown := p.runtimeStates[runtime.RuntimeID]
+	p.runtimeStates[runtime.RuntimeID] = newRuntimeState(runtime)
+	if isFirstPoll {
+		return
+	}
+
+	var events []edp.LifecycleEvent
+	if isKnown {
+		events = lifecycleEvents(&previous, runtime, time.Now())
+	} else {
+		events = lifecycleEvents(nil, runtime, time.Now())
+	}
+	for _, event := range events {
+		if err := p.sendLifecycleEvent(runtime.SubAccountID, event); err != nil {
+			p.Logger.Errorf("failed to send %s event for subAccountID: %s, runtimeID: %s: %v", event.Type, runtime.SubAccountID, runtime.RuntimeID, err)
+		}
+	}
+}
+
+// forgetLifecycle drops the state of a runtime which is not listed by KEB anymore.
+// A runtime which was billed gets a deprovisioned event at the given time.
+func (p *Process) forgetLifecycle(subAccountID, runtimeID string, deprovisionedAt time.Time) {
+	state, isKnown := p.runtimeStates[runtimeID]
+	if !isKnown {
+		return
+	}
+	delete(p.runtimeStates, runtimeID)
+	if !state.Provisioned || state.Deprovisioned {
+		return
+	}
+	event := edp.LifecycleEvent{
+		Type:       edp.LifecycleDeprovisioned,
+		RuntimeID:  runtimeID,
+		InstanceID: state.InstanceID,
+		ShootName:  state.ShootName,
+		Timestamp:  deprovisionedAt.Format(time.RFC3339),
+	}
+	if err := p.sendLifecycleEvent(subAccountID, event); err != nil {
+		p.Logger.Errorf("failed to send %s event for subAccountID: %s, runtimeID: %s: %v", event.Type, subAccountID, runtimeID, err)
+	}
+}
+
+// sendLifecycleEvent sends a lifecycle event to the lifecycle datastream of the tenant of the subaccount.
+// It is sent in the background so that a slow EDP does not hold up the polling of KEB.
+func (p Process) sendLifecycleEvent(subAccountID string, event edp.LifecycleEvent) error {
+	event.SchemaVersion = edp.LifecycleSchemaVersion
+	event.EventID = edp.NewEventID(subAccountID, event.RuntimeID, event.Type, event.Timestamp)
+	if err := edp.Validate(event); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "failed to json.Marshal lifecycle event")
+	}
+	if err := p.deliverAsync(p.EDPClient.Config.LifecycleDataStreamName, subAccountID, payload); err != nil {
+		return err
+	}
+	p.Logger.Infof("queued %s event for subAccountID: %s, runtimeID: %s", event.Type, subAccountID, event.RuntimeID)
+	return nil
+}
+
+// loadRuntimeStates loads the lifecycle states which were saved by a previous run from LifecycleDir.
+// It returns false if there are none, so that the first poll only records the states.
+func (p *Process) loadRuntimeStates() bool {
+	if p.LifecycleDir == "" {
+		return false
+	}
+	stateBytes, err := ioutil.ReadFile(filepath.Join(p.LifecycleDir, lifecycleStateFileName))
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err != nil {
+		p.Logger.Errorf("failed to read lifecycle states: %v", err)
+		return false
+	}
+	loaded := make(map[string]runtimeState)
+	if err := json.Unmarshal(stateBytes, &loaded); err != nil {
+		p.Logger.Warnf("discarding unreadable lifecycle states: %v", err)
+		return false
+	}
+	p.runtimeStates = loaded
+	return true
+}
+
+// saveRuntimeStates persists the lifecycle states in LifecycleDir, so that the transitions which happen
+// while Metris is restarted are found by the next poll
+func (p Process) saveRuntimeStates() error {
+	if p.LifecycleDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(p.LifecycleDir, lifecycleDirMode); err != nil {
+		return errors.Wrapf(err, "failed to create lifecycle dir")
+	}
+	stateBytes, err := json.Marshal(p.runtimeStates)
+	if err != nil {
+		return errors.Wrapf(err, "failed to json.Marshal lifecycle states")
+	}
+	path := filepath.Join(p.LifecycleDir, lifecycleStateFileName)
+	tmpPath := path + lifecycleTmpFileSuffix
+	if err := ioutil.WriteFile(tmpPath, stateBytes, lifecycleStateFileMode); err != nil {
+		return errors.Wrapf(err, "failed to write lifecycle states")
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return errors.Wrapf(err, "failed to write lifecycle states")
+	}
+	return nil
+}
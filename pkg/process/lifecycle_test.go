@@ -0,0 +1,261 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+	"github.com/onsi/gomega"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestLifecycleEvents(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	opTime := now.Add(-time.Hour)
+	subAccID := uuid.New().String()
+	provisioned := newRuntimeState(func() *kebruntime.RuntimeDTO {
+		runtime := metristesting.NewRuntimesDTO(subAccID, "shoot")
+		return &runtime
+	}())
+
+	testCases := []struct {
+		name           string
+		previous       *runtimeState
+		opts           []metristesting.NewRuntimeOpts
+		expectedType   []string
+		expectedOpTime bool
+	}{
+		{
+			name:     "unchanged",
+			previous: &provisioned,
+		},
+		{
+			name: "provisioned",
+			opts: []metristesting.NewRuntimeOpts{func(runtime *kebruntime.RuntimeDTO) {
+				runtime.Status.Provisioning = &kebruntime.Operation{State: "succeeded", OperationID: "op", CreatedAt: opTime}
+			}},
+			expectedType:   []string{edp.LifecycleProvisioned},
+			expectedOpTime: true,
+		},
+		{
+			name:     "still provisioning",
+			previous: nil,
+			opts: []metristesting.NewRuntimeOpts{func(runtime *kebruntime.RuntimeDTO) {
+				runtime.Status.Provisioning.State = "in progress"
+			}},
+		},
+		{
+			name:     "upgraded",
+			previous: &provisioned,
+			opts: []metristesting.NewRuntimeOpts{func(runtime *kebruntime.RuntimeDTO) {
+				runtime.Status.UpgradingKyma.Data = []kebruntime.Operation{
+					{State: "failed", OperationID: "failed-op", CreatedAt: now},
+					{State: "succeeded", OperationID: "op", CreatedAt: opTime},
+				}
+			}},
+			expectedType:   []string{edp.LifecycleUpgraded},
+			expectedOpTime: true,
+		},
+		{
+			name:     "suspended",
+			previous: &provisioned,
+			opts: []metristesting.NewRuntimeOpts{func(runtime *kebruntime.RuntimeDTO) {
+				runtime.Status.Suspension.Data = []kebruntime.Operation{{State: "succeeded", OperationID: "op", CreatedAt: opTime}}
+			}},
+			expectedType:   []string{edp.LifecycleSuspended},
+			expectedOpTime: true,
+		},
+		{
+			name:     "deprovisioned",
+			previous: &provisioned,
+			opts: []metristesting.NewRuntimeOpts{func(runtime *kebruntime.RuntimeDTO) {
+				runtime.Status.Deprovisioning = &kebruntime.Operation{State: "in progress", OperationID: "op", CreatedAt: opTime}
+			}},
+			expectedType:   []string{edp.LifecycleDeprovisioned},
+			expectedOpTime: true,
+		},
+		{
+			name:     "shoot renamed",
+			previous: &provisioned,
+			opts: []metristesting.NewRuntimeOpts{func(runtime *kebruntime.RuntimeDTO) {
+				runtime.ShootName = "renamed-shoot"
+			}},
+			expectedType: []string{edp.LifecycleShootRenamed},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runtime := metristesting.NewRuntimesDTO(subAccID, "shoot", tc.opts...)
+			events := lifecycleEvents(tc.previous, &runtime, now)
+			g.Expect(events).To(gomega.HaveLen(len(tc.expectedType)))
+			for i, event := range events {
+				g.Expect(event.Type).To(gomega.Equal(tc.expectedType[i]))
+				g.Expect(event.RuntimeID).To(gomega.Equal(runtime.RuntimeID))
+				if tc.expectedOpTime {
+					g.Expect(event.Timestamp).To(gomega.Equal(opTime.Format(time.RFC3339)))
+					g.Expect(event.OperationID).To(gomega.Equal("op"))
+				} else {
+					g.Expect(event.Timestamp).To(gomega.Equal(now.Format(time.RFC3339)))
+				}
+			}
+		})
+	}
+
+	t.Run("previous shoot name of a renamed shoot", func(t *testing.T) {
+		runtime := metristesting.NewRuntimesDTO(subAccID, "renamed-shoot")
+		events := lifecycleEvents(&provisioned, &runtime, now)
+		g.Expect(events).To(gomega.HaveLen(1))
+		g.Expect(events[0].ShootName).To(gomega.Equal("renamed-shoot"))
+		g.Expect(events[0].PreviousShootName).To(gomega.Equal("shoot"))
+	})
+}
+
+func TestTrackLifecycle(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+	knownRuntime := metristesting.NewRuntimesDTO(subAccID, "known-shoot")
+	newRuntime := metristesting.NewRuntimesDTO(subAccID, "new-shoot")
+	newRuntime.RuntimeID = uuid.New().String()
+
+	getEvents, srv := startLifecycleServer(g, subAccID)
+	defer srv.Close()
+
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), log)
+	g.Expect(err).Should(gomega.BeNil())
+	kebClient, kebSrv := newFakeKEBClient(g)
+	defer kebSrv.Close()
+	p := Process{
+		KEBClient:          kebClient,
+		EDPClient:          edpClient,
+		Queue:              workqueue.NewDelayingQueue(),
+		Cache:              gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+		Statuses:           metriscache.NewStatusStore(),
+		RuntimeGracePeriod: time.Hour,
+		Logger:             log,
+	}
+
+	// The first poll only records the states
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{knownRuntime}})
+	g.Consistently(getEvents, 100*time.Millisecond).Should(gomega.BeEmpty())
+	g.Expect(p.runtimeStates).To(gomega.HaveKey(knownRuntime.RuntimeID))
+
+	// A runtime which shows up afterwards is provisioned
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{knownRuntime, newRuntime}})
+	g.Eventually(getEvents, timeout).Should(gomega.HaveLen(1))
+	g.Expect(getEvents()[0].Type).To(gomega.Equal(edp.LifecycleProvisioned))
+	g.Expect(getEvents()[0].RuntimeID).To(gomega.Equal(newRuntime.RuntimeID))
+
+	// A runtime which went missing from KEB is deprovisioned at the time it went missing once it is evicted
+	missingSince := time.Now().Add(-2 * time.Hour)
+	runtimesPage := &kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{knownRuntime}}
+	p.reconcileCache(runtimesPage)
+	p.missingSince[newRuntime.RuntimeID] = missingSince
+	p.reconcileCache(runtimesPage)
+	g.Expect(p.isTracked(newRuntime.RuntimeID)).To(gomega.BeFalse())
+	g.Expect(p.runtimeStates).ToNot(gomega.HaveKey(newRuntime.RuntimeID))
+	g.Eventually(getEvents, timeout).Should(gomega.HaveLen(2))
+	g.Expect(getEvents()[1].Type).To(gomega.Equal(edp.LifecycleDeprovisioned))
+	g.Expect(getEvents()[1].RuntimeID).To(gomega.Equal(newRuntime.RuntimeID))
+	g.Expect(getEvents()[1].Timestamp).To(gomega.Equal(missingSince.Format(time.RFC3339)))
+}
+
+func TestTrackLifecycleAcrossRestarts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+	suspendedAt := time.Now().Add(-3 * time.Hour).UTC().Truncate(time.Second)
+	unsuspendedAt := suspendedAt.Add(time.Hour)
+	runtime := metristesting.NewRuntimesDTO(subAccID, "shoot")
+	goneRuntime := metristesting.NewRuntimesDTO(subAccID, "gone-shoot")
+	goneRuntime.RuntimeID = uuid.New().String()
+
+	getEvents, srv := startLifecycleServer(g, subAccID)
+	defer srv.Close()
+
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), log)
+	g.Expect(err).Should(gomega.BeNil())
+	kebClient, kebSrv := newFakeKEBClient(g)
+	defer kebSrv.Close()
+	dir, err := ioutil.TempDir("", "lifecycle")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	newProcess := func() *Process {
+		return &Process{
+			KEBClient:          kebClient,
+			EDPClient:          edpClient,
+			Queue:              workqueue.NewDelayingQueue(),
+			Cache:              gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+			Statuses:           metriscache.NewStatusStore(),
+			RuntimeGracePeriod: time.Hour,
+			LifecycleDir:       dir,
+			Logger:             log,
+		}
+	}
+
+	p := newProcess()
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{runtime, goneRuntime}})
+	g.Expect(p.saveRuntimeStates()).Should(gomega.BeNil())
+
+	// While Metris was down, the runtime was suspended and unsuspended and the other runtime was deprovisioned
+	runtime.Status.Suspension.Data = []kebruntime.Operation{{State: "succeeded", OperationID: "suspension", CreatedAt: suspendedAt}}
+	runtime.Status.Unsuspension.Data = []kebruntime.Operation{{State: "succeeded", OperationID: "unsuspension", CreatedAt: unsuspendedAt}}
+	runtimesPage := &kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{runtime}}
+
+	p = newProcess()
+	p.populateCacheAndQueue(runtimesPage)
+	p.reconcileCache(runtimesPage)
+	g.Eventually(getEvents, timeout).Should(gomega.HaveLen(3))
+	// The events are sent in the background without an outbox, hence in any order
+	events := getEvents()
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+	g.Expect(events[0].Type).To(gomega.Equal(edp.LifecycleSuspended))
+	g.Expect(events[0].Timestamp).To(gomega.Equal(suspendedAt.Format(time.RFC3339)))
+	g.Expect(events[1].Type).To(gomega.Equal(edp.LifecycleUnsuspended))
+	g.Expect(events[1].Timestamp).To(gomega.Equal(unsuspendedAt.Format(time.RFC3339)))
+	g.Expect(events[2].Type).To(gomega.Equal(edp.LifecycleDeprovisioned))
+	g.Expect(events[2].RuntimeID).To(gomega.Equal(goneRuntime.RuntimeID))
+	g.Expect(p.runtimeStates).ToNot(gomega.HaveKey(goneRuntime.RuntimeID))
+}
+
+// startLifecycleServer starts an EDP server which collects the lifecycle events of the subaccount
+func startLifecycleServer(g *gomega.WithT, subAccID string) (func() []edp.LifecycleEvent, *httptest.Server) {
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testLifecycleStream, edp.LifecycleSchemaVersion, subAccID, testEnv)
+	var mu sync.Mutex
+	var gotEvents []edp.LifecycleEvent
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		event := edp.LifecycleEvent{}
+		err := json.NewDecoder(req.Body).Decode(&event)
+		g.Expect(err).Should(gomega.BeNil())
+		mu.Lock()
+		gotEvents = append(gotEvents, event)
+		mu.Unlock()
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	getEvents := func() []edp.LifecycleEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]edp.LifecycleEvent(nil), gotEvents...)
+	}
+	return getEvents, srv
+}
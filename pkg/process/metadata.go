@@ -0,0 +1,37 @@
+package process
+
+import (
+	gardenerv1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+)
+
+// newRuntimeMetadata returns the metadata of a runtime in KEB which is sent along with its metrics
+func newRuntimeMetadata(runtime *kebruntime.RuntimeDTO) metriscache.RuntimeMetadata {
+	return metriscache.RuntimeMetadata{
+		GlobalAccountID: runtime.GlobalAccountID,
+		ServicePlanID:   runtime.ServicePlanID,
+		ServicePlanName: runtime.ServicePlanName,
+		Region:          runtime.ProviderRegion,
+	}
+}
+
+// setShootMetadata keeps the metadata of the shoot in the record, so it is known while the runtime is suspended
+func setShootMetadata(record *metriscache.Record, shoot *gardenerv1beta1.Shoot) {
+	record.ProviderType = shoot.Spec.Provider.Type
+	record.KubernetesVersion = shoot.Spec.Kubernetes.Version
+}
+
+// setRuntimeMetadata identifies the runtime of the record in the metric
+func setRuntimeMetadata(metric *edp.ConsumptionMetrics, record metriscache.Record) {
+	metric.RuntimeID = record.RuntimeID
+	metric.InstanceID = record.InstanceID
+	metric.GlobalAccountID = record.Runtime.GlobalAccountID
+	metric.ShootName = record.ShootName
+	metric.ServicePlanID = record.Runtime.ServicePlanID
+	metric.ServicePlanName = record.Runtime.ServicePlanName
+	metric.Region = record.Runtime.Region
+	metric.ProviderType = record.ProviderType
+	metric.KubernetesVersion = record.KubernetesVersion
+}
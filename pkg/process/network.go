@@ -0,0 +1,83 @@
+package process
+
+import (
+	"strings"
+
+	gardenerazurev1alpha1 "github.com/gardener/gardener-extension-provider-azure/pkg/apis/azure/v1alpha1"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// internalLoadBalancerAnnotations are the annotations of the providers which make a load balancer internal, i.e. it only
+// gets an IP of the VNet, with the value which enables it
+var internalLoadBalancerAnnotations = map[string]string{
+	"service.beta.kubernetes.io/azure-load-balancer-internal":     "true",
+	"service.beta.kubernetes.io/aws-load-balancer-internal":       "true",
+	"service.beta.kubernetes.io/openstack-internal-load-balancer": "true",
+	"networking.gke.io/load-balancer-type":                        "internal",
+	"cloud.google.com/load-balancer-type":                         "internal",
+}
+
+// getLoadBalancers returns the load balancers which received an ingress, public and internal ones apart.
+// The IPs which are shared by several services are counted once. The provisioned IPs keep counting every
+// service of type LoadBalancer as they are billed this way.
+func getLoadBalancers(svcList *corev1.ServiceList) edp.Networking {
+	networking := edp.Networking{}
+	if svcList == nil {
+		return networking
+	}
+	publicIPs := make(map[string]bool)
+	internalIPs := make(map[string]bool)
+	for _, svc := range svcList.Items {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		networking.ProvisionedIPs += 1
+		if len(svc.Status.LoadBalancer.Ingress) == 0 {
+			continue
+		}
+		ips := publicIPs
+		if isInternalLoadBalancer(&svc) {
+			ips = internalIPs
+			networking.InternalLoadBalancers += 1
+		} else {
+			networking.PublicLoadBalancers += 1
+		}
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			// Some providers expose the load balancer by hostname instead
+			address := ingress.IP
+			if address == "" {
+				address = ingress.Hostname
+			}
+			if address != "" {
+				ips[address] = true
+			}
+		}
+	}
+	networking.PublicLoadBalancerIPs = len(publicIPs)
+	networking.InternalLoadBalancerIPs = len(internalIPs)
+	return networking
+}
+
+// isInternalLoadBalancer checks if the load balancer of a service is internal according to the annotations of the providers
+func isInternalLoadBalancer(svc *corev1.Service) bool {
+	for annotation, internalValue := range internalLoadBalancerAnnotations {
+		if value, ok := svc.Annotations[annotation]; ok && strings.EqualFold(value, internalValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// getNATGatewayIPs returns the number of the egress IPs of the NAT gateway. Gardener creates a single one
+// unless the IPs are configured.
+func getNATGatewayIPs(infraConfig *gardenerazurev1alpha1.InfrastructureConfig) int {
+	natGateway := infraConfig.Networks.NatGateway
+	if natGateway == nil || !natGateway.Enabled {
+		return 0
+	}
+	if len(natGateway.IPAddresses) > 0 {
+		return len(natGateway.IPAddresses)
+	}
+	return 1
+}
@@ -0,0 +1,61 @@
+package process
+
+import (
+	"testing"
+
+	gardenerazurev1alpha1 "github.com/gardener/gardener-extension-provider-azure/pkg/apis/azure/v1alpha1"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetLoadBalancers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	withIngress := func(ips ...string) func(*corev1.Service) {
+		return func(svc *corev1.Service) {
+			svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+			svc.Status.LoadBalancer.Ingress = nil
+			for _, ip := range ips {
+				svc.Status.LoadBalancer.Ingress = append(svc.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{IP: ip})
+			}
+		}
+	}
+	internal := func(svc *corev1.Service) {
+		svc.Annotations = map[string]string{"service.beta.kubernetes.io/azure-load-balancer-internal": "true"}
+	}
+	svcList := &corev1.ServiceList{Items: []corev1.Service{
+		*metristesting.GetSvc("public", "foo", withIngress("20.1.1.1")),
+		// The IP is shared with the public service
+		*metristesting.GetSvc("shared", "bar", withIngress("20.1.1.1")),
+		*metristesting.GetSvc("internal", "foo", withIngress("10.250.0.10"), internal),
+		// The load balancer is not provisioned yet
+		*metristesting.GetSvc("pending", "foo", withIngress()),
+		*metristesting.GetSvc("clusterip", "foo", metristesting.WithClusterIP),
+	}}
+
+	// Every service of type LoadBalancer is a provisioned IP
+	g.Expect(getLoadBalancers(svcList)).To(gomega.Equal(edp.Networking{
+		ProvisionedIPs:          4,
+		PublicLoadBalancers:     2,
+		InternalLoadBalancers:   1,
+		PublicLoadBalancerIPs:   1,
+		InternalLoadBalancerIPs: 1,
+	}))
+	g.Expect(getLoadBalancers(nil)).To(gomega.Equal(edp.Networking{}))
+}
+
+func TestGetNATGatewayIPs(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	infraConfig := metristesting.NewInfraConfig()
+	g.Expect(getNATGatewayIPs(infraConfig)).To(gomega.Equal(0))
+
+	infraConfig.Networks.NatGateway = &gardenerazurev1alpha1.NatGatewayConfig{Enabled: true}
+	g.Expect(getNATGatewayIPs(infraConfig)).To(gomega.Equal(1))
+
+	infraConfig.Networks.NatGateway.IPAddresses = []gardenerazurev1alpha1.PublicIPReference{{Name: "ip-1"}, {Name: "ip-2"}}
+	g.Expect(getNATGatewayIPs(infraConfig)).To(gomega.Equal(2))
+
+	infraConfig.Networks.NatGateway.Enabled = false
+	g.Expect(getNATGatewayIPs(infraConfig)).To(gomega.Equal(0))
+}
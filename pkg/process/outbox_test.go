@@ -0,0 +1,182 @@
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	"github.com/kyma-incubator/metris/pkg/outbox"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReplayEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStream, testDataStreamVersion, subAccID, testEnv)
+	edpStatus := http.StatusServiceUnavailable
+	var gotPayloads []string
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if edpStatus != http.StatusCreated {
+			rw.WriteHeader(edpStatus)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		g.Expect(err).Should(gomega.BeNil())
+		gotPayloads = append(gotPayloads, string(body))
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "outbox")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	edpOutbox, err := outbox.New(dir, 10, time.Minute, log)
+	g.Expect(err).Should(gomega.BeNil())
+
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), log)
+	g.Expect(err).Should(gomega.BeNil())
+	p := Process{
+		EDPClient: edpClient,
+		Outbox:    edpOutbox,
+		Logger:    log,
+	}
+
+	var payloads []string
+	for i := 0; i < 2; i++ {
+		metric := NewMetric()
+		metric.Timestamp = fmt.Sprintf("2021-02-01T10:0%d:00Z", i)
+		payload, err := edp.EncodeConsumptionMetrics(*metric, edp.LatestConsumptionSchema)
+		g.Expect(err).Should(gomega.BeNil())
+		payloads = append(payloads, string(payload))
+		eventID, err := p.Outbox.Put(subAccID, payload)
+		g.Expect(err).Should(gomega.BeNil())
+		p.Outbox.Release(eventID)
+	}
+
+	// The events are kept while EDP is down
+	p.Outbox.Replay(p.replayEvent)
+	g.Expect(p.Outbox.Len()).To(gomega.Equal(2))
+	g.Expect(gotPayloads).To(gomega.BeEmpty())
+
+	// The events are kept while the token is not accepted
+	edpStatus = http.StatusForbidden
+	p.Outbox.Replay(p.replayEvent)
+	g.Expect(p.Outbox.Len()).To(gomega.Equal(2))
+
+	// The backlog is delivered in timestamp order once EDP recovers
+	edpStatus = http.StatusCreated
+	p.Outbox.Replay(p.replayEvent)
+	g.Expect(p.Outbox.Len()).To(gomega.Equal(0))
+	g.Expect(gotPayloads).To(gomega.Equal(payloads))
+
+	// Invalid events are dropped
+	eventID, err := p.Outbox.Put(subAccID, []byte(payloads[0]))
+	g.Expect(err).Should(gomega.BeNil())
+	p.Outbox.Release(eventID)
+	edpStatus = http.StatusBadRequest
+	p.Outbox.Replay(p.replayEvent)
+	g.Expect(p.Outbox.Len()).To(gomega.Equal(0))
+}
+
+func TestReplayEventInMultipleVersions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+
+	// Version 2 of the datastream is down at first
+	var mu sync.Mutex
+	isV2Down := true
+	gotVersions := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		version := strings.Split(strings.TrimPrefix(req.URL.Path, fmt.Sprintf("/namespaces/%s/dataStreams/%s/", testNamespace, testDataStream)), "/")[0]
+		if version == edp.ConsumptionSchemaV2 && isV2Down {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gotVersions[version] += 1
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "outbox")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	edpOutbox, err := outbox.New(dir, 10, time.Minute, log)
+	g.Expect(err).Should(gomega.BeNil())
+
+	config := newEDPConfig(srv.URL)
+	config.DataStreamVersion = "1,2"
+	edpClient, err := edp.NewClient(config, log)
+	g.Expect(err).Should(gomega.BeNil())
+	p := Process{
+		EDPClient: edpClient,
+		Outbox:    edpOutbox,
+		Logger:    log,
+	}
+
+	payload, err := edp.EncodeConsumptionMetrics(*NewMetric(), edp.LatestConsumptionSchema)
+	g.Expect(err).Should(gomega.BeNil())
+	eventID, err := p.Outbox.Put(subAccID, payload)
+	g.Expect(err).Should(gomega.BeNil())
+	_, err = p.sendEventStreamToEDP(eventID, subAccID, payload)
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	p.Outbox.Release(eventID)
+
+	// Only the version which did not accept the event is replayed
+	mu.Lock()
+	isV2Down = false
+	mu.Unlock()
+	p.Outbox.Replay(p.replayEvent)
+	g.Expect(p.Outbox.Len()).To(gomega.Equal(0))
+	g.Expect(gotVersions).To(gomega.Equal(map[string]int{edp.ConsumptionSchemaV1: 1, edp.ConsumptionSchemaV2: 1}))
+}
+
+func TestDeliverAsync(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+
+	dir, err := ioutil.TempDir("", "outbox")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	edpOutbox, err := outbox.New(dir, 10, time.Minute, log)
+	g.Expect(err).Should(gomega.BeNil())
+	p := Process{
+		Outbox: edpOutbox,
+		Logger: log,
+	}
+
+	// The event is left to the replayer instead of being sent by the caller
+	err = p.deliverAsync("", subAccID, []byte(`{"foo":"bar"}`))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(p.Outbox.Len()).To(gomega.Equal(1))
+	var replayed []outbox.Event
+	p.Outbox.Replay(func(event outbox.Event) error {
+		replayed = append(replayed, event)
+		return nil
+	})
+	g.Expect(replayed).To(gomega.HaveLen(1))
+	g.Expect(replayed[0].Tenant).To(gomega.Equal(subAccID))
+	g.Expect(string(replayed[0].Payload)).To(gomega.Equal(`{"foo":"bar"}`))
+}
@@ -2,24 +2,27 @@ package process
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/kyma-incubator/metris/pkg/aggregation"
 	"github.com/kyma-incubator/metris/pkg/keb"
 
 	gardenerv1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
 	metriscache "github.com/kyma-incubator/metris/pkg/cache"
-	gardenersecret "github.com/kyma-incubator/metris/pkg/gardener/secret"
+	gardenerkubeconfig "github.com/kyma-incubator/metris/pkg/gardener/kubeconfig"
 	gardenershoot "github.com/kyma-incubator/metris/pkg/gardener/shoot"
+	"github.com/kyma-incubator/metris/pkg/outbox"
 	skrnode "github.com/kyma-incubator/metris/pkg/skr/node"
+	skrpv "github.com/kyma-incubator/metris/pkg/skr/pv"
 	skrpvc "github.com/kyma-incubator/metris/pkg/skr/pvc"
 	skrsvc "github.com/kyma-incubator/metris/pkg/skr/svc"
 
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"k8s.io/client-go/util/workqueue"
 
@@ -32,121 +35,190 @@ import (
 )
 
 type Process struct {
-	KEBClient       *keb.Client
-	EDPClient       *edp.Client
-	Queue           workqueue.DelayingInterface
-	ShootClient     *gardenershoot.Client
-	SecretClient    *gardenersecret.Client
-	Cache           *cache.Cache
-	Providers       *Providers
-	ScrapeInterval  time.Duration
+	KEBClient         *keb.Client
+	EDPClient         *edp.Client
+	Outbox            *outbox.Outbox
+	Aggregator        *aggregation.Aggregator
+	Batcher           *edp.Batcher
+	Queue             workqueue.DelayingInterface
+	ShootClient       *gardenershoot.Client
+	KubeconfigManager *gardenerkubeconfig.Manager
+	Cache             *cache.Cache
+	Statuses          *metriscache.StatusStore
+	Providers         *Providers
+	ScrapeInterval    time.Duration
+	// RuntimeGracePeriod is how long a runtime may be missing from KEB before it is not tracked anymore
+	RuntimeGracePeriod time.Duration
+	// LifecycleDir is where the lifecycle states of the runtimes are kept across restarts, nowhere if empty
+	LifecycleDir    string
 	WorkersPoolSize int
 	NodeConfig      skrnode.ConfigInf
 	PVCConfig       skrpvc.ConfigInf
+	PVConfig        skrpv.ConfigInf
 	SvcConfig       skrsvc.ConfigInf
 	Logger          *logrus.Logger
-}
 
-const shootKubeconfigKey = "kubeconfig"
+	// missingSince keeps when the tracked runtimes went missing from KEB
+	missingSince map[string]time.Time
+	// runtimeStates keeps the state of the runtimes from the previous poll of KEB to find their lifecycle transitions
+	runtimeStates map[string]runtimeState
+}
 
-func (p Process) generateRecordWithMetrics(identifier int, subAccountID string) (record metriscache.Record, err error) {
+func (p Process) generateRecordWithMetrics(identifier int, runtimeID string) (record metriscache.Record, err error) {
 	ctx := context.Background()
 	var ok bool
 
-	obj, isFound := p.Cache.Get(subAccountID)
+	// Keep track of the stage which failed to generate the metric
+	stage := stageCache
+	defer func() {
+		if err != nil {
+			err = scrapeError{stage: stage, err: err}
+		}
+	}()
+
+	defer p.Queue.Done(runtimeID)
+	obj, isFound := p.Cache.Get(runtimeID)
 	if !isFound {
-		err = fmt.Errorf("subAccountID was not found in cache")
+		err = fmt.Errorf("runtimeID was not found in cache")
 		return
 	}
 
-	defer p.Queue.Done(subAccountID)
 	if record, ok = obj.(metriscache.Record); !ok {
 		err = fmt.Errorf("bad item from cache, could not cast to a record obj")
 		return
 	}
 	p.Logger.Debugf("[worker: %d] record found from cache: %+v", identifier, record)
 
+	if record.Suspended {
+		// The cluster of a suspended runtime is gone hence there is nothing to scrape
+		p.Logger.Debugf("[worker: %d] skipped scraping suspended runtimeID: %s", identifier, runtimeID)
+		record.Metric = suspendedMetric(record, p.ScrapeInterval)
+		return
+	}
+
 	shootName := record.ShootName
 
-	if record.KubeConfig == "" {
-		// Get shoot kubeconfig secret
-		var secret *corev1.Secret
-		secret, err = p.SecretClient.Get(ctx, shootName)
-		if err != nil {
-			return
-		}
+	// Get shoot CR
+	stage = stageShoot
+	var shoot *gardenerv1beta1.Shoot
+	shoot, err = p.ShootClient.Get(ctx, shootName)
+	if err != nil {
+		return
+	}
+	setShootMetadata(&record, shoot)
+	if isHibernated(shoot) {
+		// The API server of a hibernated shoot is scaled down
+		p.Logger.Debugf("[worker: %d] skipped scraping hibernated shoot: %s", identifier, shootName)
+		record.Metric = suspendedMetric(record, p.ScrapeInterval)
+		return
+	}
 
-		record.KubeConfig = string(secret.Data[shootKubeconfigKey])
-		if record.KubeConfig == "" {
-			err = fmt.Errorf("kubeconfig for shoot not found")
+	// Get shoot kubeconfig
+	stage = stageKubeconfig
+	var kubeconfig string
+	kubeconfig, err = p.KubeconfigManager.Get(ctx, shootName)
+	if err != nil {
+		return
+	}
+
+	stage = stageSKR
+	nodes, pvcList, pvList, svcList, err := p.listSKRResources(ctx, kubeconfig)
+	if isUnauthorized(err) {
+		// The credentials might have been rotated hence retry once with a fresh kubeconfig
+		p.Logger.Infof("[worker: %d] refreshing kubeconfig for shoot: %s as it was rejected: %v", identifier, shootName, err)
+		stage = stageKubeconfig
+		kubeconfig, err = p.KubeconfigManager.Refresh(ctx, shootName)
+		if err != nil {
 			return
 		}
+		stage = stageSKR
+		nodes, pvcList, pvList, svcList, err = p.listSKRResources(ctx, kubeconfig)
 	}
-
-	// Get shoot CR
-	var shoot *gardenerv1beta1.Shoot
-	shoot, err = p.ShootClient.Get(ctx, shootName)
 	if err != nil {
 		return
 	}
 
+	// Create input
+	input := Input{
+		shoot:    shoot,
+		nodeList: nodes,
+		pvcList:  pvcList,
+		pvList:   pvList,
+		svcList:  svcList,
+
+		scrapeInterval: p.ScrapeInterval,
+	}
+	stage = stageParse
+	metric, err := input.Parse(p.Providers)
+	if metric != nil {
+		setRuntimeMetadata(metric, record)
+		metric.EventID = edp.NewConsumptionEventID(record.SubAccountID, edp.VariantSnapshot, *metric)
+	}
+	record.Metric = metric
+	record.RetainedVolumes = getRetainedVolumes(pvcList, pvList)
+	return
+}
+
+// listSKRResources lists the nodes, PVCs, PVs and services of a SKR
+func (p Process) listSKRResources(ctx context.Context, kubeconfig string) (*corev1.NodeList, *corev1.PersistentVolumeClaimList, *corev1.PersistentVolumeList, *corev1.ServiceList, error) {
 	// Get nodes dynamic client
-	nodesClient, err := p.NodeConfig.NewClient(record.KubeConfig)
+	nodesClient, err := p.NodeConfig.NewClient(kubeconfig)
 	if err != nil {
-		return
+		return nil, nil, nil, nil, err
 	}
 
 	// Get nodes
-	var nodes *corev1.NodeList
-	nodes, err = nodesClient.List(ctx)
+	nodes, err := nodesClient.List(ctx)
 	if err != nil {
-		return
+		return nil, nil, nil, nil, err
 	}
 
 	if len(nodes.Items) == 0 {
-		err = fmt.Errorf("no nodes to process")
-		return
+		return nil, nil, nil, nil, fmt.Errorf("no nodes to process")
 	}
 
 	// Get PVCs
-	pvcClient, err := p.PVCConfig.NewClient(record.KubeConfig)
+	pvcClient, err := p.PVCConfig.NewClient(kubeconfig)
 	if err != nil {
-		return
+		return nil, nil, nil, nil, err
 	}
-	var pvcList *corev1.PersistentVolumeClaimList
-	pvcList, err = pvcClient.List(ctx)
+	pvcList, err := pvcClient.List(ctx)
 	if err != nil {
-		return
+		return nil, nil, nil, nil, err
 	}
 
-	// Get Svcs
-	var svcList *corev1.ServiceList
-	svcClient, err := p.SvcConfig.NewClient(record.KubeConfig)
+	// Get PVs
+	pvClient, err := p.PVConfig.NewClient(kubeconfig)
 	if err != nil {
-		return
+		return nil, nil, nil, nil, err
 	}
-	svcList, err = svcClient.List(ctx)
+	pvList, err := pvClient.List(ctx)
 	if err != nil {
-		return
+		return nil, nil, nil, nil, err
 	}
 
-	// Create input
-	input := Input{
-		shoot:    shoot,
-		nodeList: nodes,
-		pvcList:  pvcList,
-		svcList:  svcList,
+	// Get Svcs
+	svcClient, err := p.SvcConfig.NewClient(kubeconfig)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
-	metric, err := input.Parse(p.Providers)
-	record.Metric = metric
-	return
+	svcList, err := svcClient.List(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return nodes, pvcList, pvList, svcList, nil
+}
+
+// isUnauthorized checks if the SKR API server rejected the credentials
+func isUnauthorized(err error) bool {
+	return k8serrors.IsUnauthorized(err) || k8serrors.IsForbidden(err)
 }
 
 // getOldRecordIfMetricExists gets old record from cache if old metric exists
-func (p Process) getOldRecordIfMetricExists(subAccountID string) (*metriscache.Record, error) {
-	oldRecordObj, found := p.Cache.Get(subAccountID)
+func (p Process) getOldRecordIfMetricExists(runtimeID string) (*metriscache.Record, error) {
+	oldRecordObj, found := p.Cache.Get(runtimeID)
 	if !found {
-		notFoundErr := fmt.Errorf("subAccountID: %s not found", subAccountID)
+		notFoundErr := fmt.Errorf("runtimeID: %s not found", runtimeID)
 		p.Logger.Error(notFoundErr)
 		return nil, notFoundErr
 	}
@@ -156,7 +228,7 @@ func (p Process) getOldRecordIfMetricExists(subAccountID string) (*metriscache.R
 			return &oldRecord, nil
 		}
 	}
-	notFoundErr := fmt.Errorf("old metrics for subAccountID: %s not found", subAccountID)
+	notFoundErr := fmt.Errorf("old metrics for runtimeID: %s not found", runtimeID)
 	p.Logger.Error(notFoundErr)
 	return nil, notFoundErr
 }
@@ -169,14 +241,19 @@ func (p *Process) pollKEBForRuntimes() {
 		p.Logger.Fatalf("failed to create a new request for KEB: %v", err)
 	}
 	for {
-		runtimesPage, err := p.KEBClient.GetAllRuntimes(kebReq)
+		runtimesPage, excludedRuntimeIDs, err := p.KEBClient.GetAllRuntimes(kebReq)
 		if err != nil {
 			p.Logger.Errorf("failed to get runtimes from KEB: %v", err)
 			time.Sleep(p.KEBClient.Config.PollWaitDuration)
 			continue
 		}
 		p.Logger.Debugf("num of runtimes are: %d", runtimesPage.Count)
+		p.untrackExcluded(excludedRuntimeIDs)
 		p.populateCacheAndQueue(runtimesPage)
+		p.reconcileCache(runtimesPage)
+		if err := p.saveRuntimeStates(); err != nil {
+			p.Logger.Errorf("failed to save lifecycle states: %v", err)
+		}
 		p.Logger.Debugf("length of the cache after KEB is done populating: %d", p.Cache.ItemCount())
 		p.Logger.Infof("waiting to poll KEB again after %v....", p.KEBClient.Config.PollWaitDuration)
 		time.Sleep(p.KEBClient.Config.PollWaitDuration)
@@ -191,6 +268,11 @@ func (p Process) Start() {
 		p.pollKEBForRuntimes()
 	}()
 
+	// Deliver the events which could not be sent by the workers once EDP recovers
+	go p.Outbox.Run(context.Background(), p.replayEvent)
+	go p.Aggregator.Run(context.Background(), p.sendUsageWindow)
+	go p.Batcher.Run(context.Background())
+
 	for i := 0; i < p.WorkersPoolSize; i++ {
 		j := i
 		go func() {
@@ -207,104 +289,283 @@ func (p *Process) execute(identifier int) {
 
 	for {
 		var payload []byte
-		// Pick up a subAccountID to process from queue
-		subAccountIDObj, _ := p.Queue.Get()
+		// Pick up a runtimeID to process from queue
+		runtimeIDObj, _ := p.Queue.Get()
 		// TODO Implement cleanup holistically in #kyma-project/control-plane/issues/512
 		//if isShuttingDown {
 		//	//p.Cleanup()
 		//	return
 		//}
-		subAccountID := fmt.Sprintf("%v", subAccountIDObj)
-		if strings.TrimSpace(subAccountID) == "" {
-			p.Logger.Warnf("[worker: %d] cannot work with empty subAccountID", identifier)
+		runtimeID := fmt.Sprintf("%v", runtimeIDObj)
+		if strings.TrimSpace(runtimeID) == "" {
+			p.Logger.Warnf("[worker: %d] cannot work with empty runtimeID", identifier)
 
 			// Nothing to do further
 			continue
 		}
-		p.Logger.Debugf("[worker: %d] subaccid: %v is fetched from queue", identifier, subAccountIDObj)
+		p.Logger.Debugf("[worker: %d] runtimeID: %v is fetched from queue", identifier, runtimeIDObj)
 
-		record, isOldMetricValid, err := p.getRecordWithOldOrNewMetric(identifier, subAccountID)
+		record, isOldMetricValid, err := p.getRecordWithOldOrNewMetric(identifier, runtimeID)
 		if err != nil {
-			p.Logger.Errorf("[worker: %d] no metric found/generated for subaccount id: %v", identifier, err)
+			p.Logger.Errorf("[worker: %d] no metric found/generated for runtime id: %v", identifier, err)
 
-			p.Queue.AddAfter(subAccountID, p.ScrapeInterval)
-			p.Logger.Debugf("[worker: %d] successfully requed after %v for subAccountID %s", identifier, p.ScrapeInterval, subAccountID)
+			if !p.isTracked(runtimeID) {
+				// The runtime was evicted in the meantime
+				p.Statuses.Delete(runtimeID)
+				p.Logger.Infof("[worker: %d] dropped untracked runtimeID: %s from queue", identifier, runtimeID)
+				continue
+			}
+			p.requeue(identifier, runtimeID)
 
 			// Nothing to do further
 			continue
 		}
 
-		// Convert metric to JSON
-		payload, err = json.Marshal(*record.Metric)
+		// Validate the metric and convert it to JSON
+		payload, err = edp.EncodeConsumptionMetrics(*record.Metric, edp.LatestConsumptionSchema)
 		if err != nil {
-			p.Logger.Errorf("[worker: %d] failed to json.Marshal metric for subaccount id: %v", identifier, err)
+			p.Logger.Errorf("[worker: %d] failed to encode metric for runtime id: %v", identifier, err)
+			p.recordFailure(runtimeID, stageMarshal, err)
 
-			p.Queue.AddAfter(subAccountID, p.ScrapeInterval)
-			p.Logger.Debugf("[worker: %d] successfully requed after %v for subAccountID %s", identifier, p.ScrapeInterval, subAccountID)
+			p.requeue(identifier, runtimeID)
 
 			// Nothing to do further
 			continue
 		}
 
-		// Send metrics to EDP
+		if !isOldMetricValid {
+			// Only fresh snapshots count towards the time-weighted usage
+			p.Aggregator.Observe(record.SubAccountID, runtimeID, record.Metric, time.Now())
+		}
+
 		// Note: EDP refers SubAccountID as tenant
-		p.Logger.Debugf("[worker: %d] sending EventStreamToEDP: tenant: %s payload: %s", identifier, subAccountID, string(payload))
-		err = p.sendEventStreamToEDP(subAccountID, payload)
-		if err != nil {
-			p.Logger.Errorf("[worker: %d] failed to send metric to EDP for subAccountID: %s, event-stream: %s, with err: %v", identifier, subAccountID, string(payload), err)
+		tenant := record.SubAccountID
 
-			p.Queue.AddAfter(subAccountID, p.ScrapeInterval)
-			p.Logger.Debugf("[worker: %d] successfully requed after %v for subAccountID %s", identifier, p.ScrapeInterval, subAccountID)
+		// Persist the event before sending so that it is not lost if EDP is down
+		eventID, err := p.Outbox.Put(tenant, payload)
+		if err != nil {
+			p.Logger.Errorf("[worker: %d] failed to write event to outbox for subAccountID: %s: %v", identifier, tenant, err)
+		}
 
-			// Nothing to do further hence continue
+		if p.Outbox.HasOlderPending(tenant, eventID) {
+			// The older events of the tenant have to reach EDP first, hence leave this one to the replayer
+			p.Outbox.Release(eventID)
+			p.Logger.Infof("[worker: %d] queued event stream in outbox behind undelivered events for subAccountID: %s", identifier, tenant)
+			p.saveRecord(identifier, record, isOldMetricValid)
+			p.requeue(identifier, runtimeID)
 			continue
 		}
-		p.Logger.Infof("[worker: %d] successfully sent event stream for subaccountID: %s, shoot: %s", identifier, subAccountID, record.ShootName)
 
-		if !isOldMetricValid {
-			p.Cache.Set(record.SubAccountID, *record, cache.NoExpiration)
-			p.Logger.Debugf("[worker: %d] successfully saved metric for subAccountID %s", identifier, record.SubAccountID)
-			p.Logger.Infof("[worker: %d] successfully saved metric for subAccountID %s", identifier, record.SubAccountID)
+		// Send metrics to EDP
+		p.Logger.Debugf("[worker: %d] sending EventStreamToEDP: tenant: %s runtimeID: %s payload: %s", identifier, tenant, runtimeID, string(payload))
+		if p.Batcher != nil {
+			// The result is handled once the batch was sent
+			p.Batcher.Submit(tenant, payload, func(statusCode int, deliveredVersions []string, err error) {
+				for _, version := range deliveredVersions {
+					p.markDelivered(eventID, version)
+				}
+				p.handleSendResult(identifier, record, isOldMetricValid, eventID, payload, statusCode, err)
+			})
+		} else {
+			statusCode, err := p.sendEventStreamToEDP(eventID, tenant, payload)
+			p.handleSendResult(identifier, record, isOldMetricValid, eventID, payload, statusCode, err)
+		}
+
+		// Requeue the runtimeID anyway
+		p.requeue(identifier, runtimeID)
+	}
+}
+
+// handleSendResult records the result of sending an event stream to EDP and keeps the outbox and the cache up-to-date
+func (p Process) handleSendResult(identifier int, record *metriscache.Record, isOldMetricValid bool, eventID string, payload []byte, statusCode int, err error) {
+	runtimeID := record.RuntimeID
+	p.recordSent(runtimeID, statusCode, isOldMetricValid, err)
+	if err != nil {
+		p.Logger.Errorf("[worker: %d] failed to send metric to EDP for subAccountID: %s, runtimeID: %s, event-stream: %s, with err: %v", identifier, record.SubAccountID, runtimeID, string(payload), err)
+
+		if edp.IsRejected(err) {
+			// EDP will never accept the event hence there is no point in replaying it
+			if err := p.Outbox.Delete(eventID); err != nil {
+				p.Logger.Errorf("[worker: %d] failed to delete rejected event from outbox for runtimeID: %s: %v", identifier, runtimeID, err)
+			}
+			return
 		}
 
-		// Requeue the subAccountID anyway
-		p.Logger.Debugf("[worker: %d] successfully requed after %v for subAccountID %s", identifier, p.ScrapeInterval, subAccountID)
-		p.Queue.AddAfter(subAccountID, p.ScrapeInterval)
+		// The replayer delivers the event once EDP recovers
+		p.Outbox.Release(eventID)
+		return
+	}
+	p.Logger.Infof("[worker: %d] successfully sent event stream for subAccountID: %s, runtimeID: %s, shoot: %s", identifier, record.SubAccountID, runtimeID, record.ShootName)
+	if err := p.Outbox.Delete(eventID); err != nil {
+		p.Logger.Errorf("[worker: %d] failed to delete sent event from outbox for runtimeID: %s: %v", identifier, runtimeID, err)
+	}
+
+	p.saveRecord(identifier, record, isOldMetricValid)
+}
+
+// saveRecord saves a freshly generated metric in the cache
+func (p Process) saveRecord(identifier int, record *metriscache.Record, isOldMetricValid bool) {
+	if !isOldMetricValid {
+		// Do not bring back a runtime which stopped being tracked in the meantime and keep the changes from KEB
+		obj, isFound := p.Cache.Get(record.RuntimeID)
+		current, ok := obj.(metriscache.Record)
+		if !isFound || !ok {
+			p.Logger.Debugf("[worker: %d] skipped saving metric for untracked runtimeID %s", identifier, record.RuntimeID)
+			return
+		}
+		current.Metric = record.Metric
+		current.RetainedVolumes = record.RetainedVolumes
+		current.ProviderType = record.ProviderType
+		current.KubernetesVersion = record.KubernetesVersion
+		if err := p.Cache.Replace(record.RuntimeID, current, cache.NoExpiration); err != nil {
+			p.Logger.Debugf("[worker: %d] skipped saving metric for untracked runtimeID %s", identifier, record.RuntimeID)
+			return
+		}
+		p.Logger.Debugf("[worker: %d] successfully saved metric for runtimeID %s", identifier, record.RuntimeID)
+		p.Logger.Infof("[worker: %d] successfully saved metric for runtimeID %s", identifier, record.RuntimeID)
 	}
 }
 
-func (p Process) getRecordWithOldOrNewMetric(identifier int, subAccountID string) (*metriscache.Record, bool, error) {
-	record, err := p.generateRecordWithMetrics(identifier, subAccountID)
+// requeue queues the runtimeID again to be scraped after the scrape interval
+func (p Process) requeue(identifier int, runtimeID string) {
+	p.Queue.AddAfter(runtimeID, p.ScrapeInterval)
+	p.recordNextScrape(runtimeID, time.Now().Add(p.ScrapeInterval))
+	p.Logger.Debugf("[worker: %d] successfully requed after %v for runtimeID %s", identifier, p.ScrapeInterval, runtimeID)
+}
+
+func (p Process) getRecordWithOldOrNewMetric(identifier int, runtimeID string) (*metriscache.Record, bool, error) {
+	record, err := p.generateRecordWithMetrics(identifier, runtimeID)
 	if err != nil {
-		p.Logger.Errorf("failed to generate new metric for subaccountID: %v, err: %v", subAccountID, err)
+		p.Logger.Errorf("failed to generate new metric for runtimeID: %v, err: %v", runtimeID, err)
+		p.recordFailure(runtimeID, errorStage(err), err)
 		// Get old data
-		oldRecord, err := p.getOldRecordIfMetricExists(subAccountID)
+		oldRecord, err := p.getOldRecordIfMetricExists(runtimeID)
 		if err != nil {
 			// Nothing to do
-			return nil, false, errors.Wrapf(err, "failed to get getOldMetric for subaccountID: %s", subAccountID)
+			return nil, false, errors.Wrapf(err, "failed to get getOldMetric for runtimeID: %s", runtimeID)
 		}
 		return oldRecord, true, nil
 	}
+	p.recordScrapeSuccess(runtimeID)
 	return &record, false, nil
 }
 
-func (p Process) sendEventStreamToEDP(tenant string, payload []byte) error {
-	edpRequest, err := p.EDPClient.NewRequest(tenant)
+// sendEventStreamToEDP sends the payload of an event of the outbox to EDP and returns the HTTP status code of the
+// last response if there was one
+func (p Process) sendEventStreamToEDP(eventID, tenant string, payload []byte) (int, error) {
+	return p.sendEvent(outbox.Event{ID: eventID, Tenant: tenant, Payload: payload})
+}
+
+// sendEvent sends the payload of an event to its datastream of EDP, the consumption metrics datastream if empty.
+// The consumption metrics are sent in every configured schema version but the ones which already accepted the event.
+// Every version which accepts it is recorded in the outbox, so that a replay does not send it there again.
+func (p Process) sendEvent(event outbox.Event) (int, error) {
+	versionedPayloads, err := p.EDPClient.VersionedPayloads(event.DataStream, event.Payload)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create a new request for EDP")
+		return 0, errors.Wrapf(err, "failed to encode event-stream for EDP")
 	}
 
-	resp, err := p.EDPClient.Send(edpRequest, payload)
+	statusCode := 0
+	for _, versionedPayload := range versionedPayloads {
+		if isDelivered(event.DeliveredVersions, versionedPayload.Version) {
+			continue
+		}
+		edpRequest, err := p.EDPClient.NewRequestForDataStream(event.DataStream, versionedPayload.Version, event.Tenant)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to create a new request for EDP")
+		}
+
+		resp, err := p.EDPClient.Send(edpRequest, versionedPayload.Payload)
+		if err != nil {
+			var respErr edp.ResponseError
+			if errors.As(err, &respErr) {
+				return respErr.StatusCode, errors.Wrapf(err, "failed to send event-stream to EDP")
+			}
+			return 0, errors.Wrapf(err, "failed to send event-stream to EDP")
+		}
+
+		if !isSuccess(resp.StatusCode) {
+			return resp.StatusCode, fmt.Errorf("failed to send event-stream to EDP as it returned HTTP: %d", resp.StatusCode)
+		}
+		statusCode = resp.StatusCode
+		p.markDelivered(event.ID, versionedPayload.Version)
+	}
+	return statusCode, nil
+}
+
+// markDelivered records in the outbox that a datastream version accepted an event
+func (p Process) markDelivered(eventID, version string) {
+	if err := p.Outbox.MarkDelivered(eventID, version); err != nil {
+		p.Logger.Errorf("failed to record delivery of event %s in version %s in outbox: %v", eventID, version, err)
+	}
+}
+
+// isDelivered checks if a datastream version is one of the versions which already accepted an event
+func isDelivered(deliveredVersions []string, version string) bool {
+	for _, delivered := range deliveredVersions {
+		if delivered == version {
+			return true
+		}
+	}
+	return false
+}
+
+// replayEvent sends an event from the outbox to EDP
+func (p Process) replayEvent(event outbox.Event) error {
+	_, err := p.sendEvent(event)
+	if edp.IsRejected(err) {
+		return errors.Wrapf(outbox.ErrRejected, "%v", err)
+	}
+	return err
+}
+
+// deliver sends an event which is not tied to a scrape to a datastream of EDP.
+// If EDP cannot be reached, the event is left in the outbox to be replayed.
+func (p Process) deliver(dataStream, tenant string, payload []byte) error {
+	eventID, err := p.Outbox.PutForDataStream(dataStream, tenant, payload)
 	if err != nil {
-		return errors.Wrapf(err, "failed to send event-stream to EDP")
+		p.Logger.Errorf("failed to write event to outbox for subAccountID: %s: %v", tenant, err)
+	}
+	if p.Outbox.HasOlderPending(tenant, eventID) {
+		// The event has to reach EDP after the older events of the tenant
+		p.Outbox.Release(eventID)
+		return nil
 	}
 
-	if !isSuccess(resp.StatusCode) {
-		return fmt.Errorf("failed to send event-stream to EDP as it returned HTTP: %d", resp.StatusCode)
+	_, err = p.sendEvent(outbox.Event{ID: eventID, DataStream: dataStream, Tenant: tenant, Payload: payload})
+	if err != nil {
+		if !edp.IsRejected(err) {
+			p.Outbox.Release(eventID)
+		} else if deleteErr := p.Outbox.Delete(eventID); deleteErr != nil {
+			p.Logger.Errorf("failed to delete rejected event from outbox for subAccountID: %s: %v", tenant, deleteErr)
+		}
+		return err
+	}
+	if err := p.Outbox.Delete(eventID); err != nil {
+		p.Logger.Errorf("failed to delete sent event from outbox for subAccountID: %s: %v", tenant, err)
 	}
 	return nil
 }
 
+// deliverAsync sends an event which is not tied to a scrape without blocking the caller. The event is left in the
+// outbox for the replayer, or it is sent in the background if the outbox is disabled.
+func (p Process) deliverAsync(dataStream, tenant string, payload []byte) error {
+	if p.Outbox == nil {
+		go func() {
+			if err := p.deliver(dataStream, tenant, payload); err != nil {
+				p.Logger.Errorf("failed to send event to EDP for subAccountID: %s: %v", tenant, err)
+			}
+		}()
+		return nil
+	}
+	eventID, err := p.Outbox.PutForDataStream(dataStream, tenant, payload)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write event to outbox")
+	}
+	p.Outbox.Release(eventID)
+	return nil
+}
+
 func isSuccess(status int) bool {
 	if status >= http.StatusOK && status < http.StatusMultipleChoices {
 		return true
@@ -321,45 +582,75 @@ func isClusterTrackable(runtime *kebruntime.RuntimeDTO) bool {
 	return false
 }
 
-// populateCacheAndQueue populates Cache and Queue with new runtimes and deletes the runtimes which should not be tracked
+// populateCacheAndQueue populates Cache and Queue with new runtimes and deletes the runtimes which should not be tracked.
+// The runtimes are keyed by runtime ID as a subaccount can have multiple runtimes. Their lifecycle transitions are sent
+// along the way.
 func (p *Process) populateCacheAndQueue(runtimes *kebruntime.RuntimesPage) {
+	isFirstPoll := p.runtimeStates == nil
+	if isFirstPoll {
+		p.runtimeStates = make(map[string]runtimeState)
+		isFirstPoll = !p.loadRuntimeStates()
+	}
 
 	for _, runtime := range runtimes.Data {
-		if runtime.SubAccountID == "" {
+		if runtime.SubAccountID == "" || runtime.RuntimeID == "" {
 			continue
 		}
-		recordObj, isFound := p.Cache.Get(runtime.SubAccountID)
+		p.trackLifecycle(&runtime, isFirstPoll)
+		recordObj, isFound := p.Cache.Get(runtime.RuntimeID)
 		if isClusterTrackable(&runtime) {
 			newRecord := metriscache.Record{
 				SubAccountID: runtime.SubAccountID,
+				RuntimeID:    runtime.RuntimeID,
+				InstanceID:   runtime.InstanceID,
 				ShootName:    runtime.ShootName,
-				KubeConfig:   "",
+				Runtime:      newRuntimeMetadata(&runtime),
+				Suspended:    isSuspended(&runtime),
 				Metric:       nil,
 			}
 			if !isFound {
-				err := p.Cache.Add(runtime.SubAccountID, newRecord, cache.NoExpiration)
+				err := p.Cache.Add(runtime.RuntimeID, newRecord, cache.NoExpiration)
 				if err != nil {
-					p.Logger.Errorf("failed to add subAccountID: %v to cache hence skipping queueing it", err)
+					p.Logger.Errorf("failed to add runtimeID: %v to cache hence skipping queueing it", err)
 					continue
 				}
-				p.Queue.Add(runtime.SubAccountID)
-				p.Logger.Debugf("Queued and added to cache: %v", runtime.SubAccountID)
+				p.Queue.Add(runtime.RuntimeID)
+				p.Logger.Debugf("Queued and added to cache: %v", runtime.RuntimeID)
 				continue
 			}
 
 			// Cluster is trackable and exists in the cache
 			if record, ok := recordObj.(metriscache.Record); ok {
-				if record.ShootName != runtime.ShootName {
-					// The shootname has changed hence the record in the cache is not valid anymore
-					// No need to queue as the subAccountID already exists in queue
-					p.Cache.Set(runtime.SubAccountID, newRecord, cache.NoExpiration)
-					p.Logger.Debugf("Resetted the values in cache: %v", runtime.SubAccountID)
+				isSuspensionChanged := record.Suspended != newRecord.Suspended
+				if record.ShootName != runtime.ShootName || record.SubAccountID != runtime.SubAccountID {
+					// The shootname or subaccount has changed hence the record in the cache is not valid anymore.
+					// An unsuspension creates a new shoot, so the new record carries the suspension as well.
+					// No need to queue as the runtimeID already exists in queue
+					p.Cache.Set(runtime.RuntimeID, newRecord, cache.NoExpiration)
+					if record.ShootName != runtime.ShootName {
+						// The kubeconfig of the old shoot is not used anymore
+						p.KubeconfigManager.Invalidate(record.ShootName)
+					}
+					p.Logger.Debugf("Resetted the values in cache: %v", runtime.RuntimeID)
+				} else if record.Runtime != newRecord.Runtime || isSuspensionChanged {
+					// The plan or the region of the runtime has changed, which is sent from the next scrape on.
+					// The last metric and the retained volumes are kept across the suspension.
+					record.Runtime = newRecord.Runtime
+					record.Suspended = newRecord.Suspended
+					p.Cache.Set(runtime.RuntimeID, record, cache.NoExpiration)
+					p.Logger.Debugf("Updated the runtime metadata in cache: %v", runtime.RuntimeID)
+				}
+				if isSuspensionChanged {
+					p.Logger.Infof("runtimeID: %s is suspended: %v", runtime.RuntimeID, newRecord.Suspended)
+					if !newRecord.Suspended {
+						// Resume scraping right away
+						p.Queue.Add(runtime.RuntimeID)
+					}
 				}
 			}
 		} else {
 			if isFound {
-				p.Cache.Delete(runtime.SubAccountID)
-				p.Logger.Debugf("Deleted subAccountID: %v", runtime.SubAccountID)
+				p.evict(runtime.RuntimeID)
 			}
 		}
 	}
@@ -3,8 +3,10 @@ package process
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 
 	skrsvc "github.com/kyma-incubator/metris/pkg/skr/svc"
 
+	skrpv "github.com/kyma-incubator/metris/pkg/skr/pv"
 	skrpvc "github.com/kyma-incubator/metris/pkg/skr/pvc"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -27,6 +30,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
 
+	gardenerkubeconfig "github.com/kyma-incubator/metris/pkg/gardener/kubeconfig"
 	gardenersecret "github.com/kyma-incubator/metris/pkg/gardener/secret"
 
 	"github.com/kyma-incubator/metris/pkg/edp"
@@ -58,8 +62,9 @@ const (
 	// EDP related variables
 	//testTenant            = "testTenant"
 	testDataStream        = "dataStream"
+	testLifecycleStream   = "lifecycleStream"
 	testNamespace         = "namespace"
-	testDataStreamVersion = "v1"
+	testDataStreamVersion = "2"
 	testToken             = "token"
 	testEnv               = "env"
 	retryCount            = 1
@@ -68,29 +73,29 @@ const (
 func TestGetOldRecordIfMetricExists(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
-	expectedSubAccIDToExist := uuid.New().String()
+	expectedRuntimeIDToExist := uuid.New().String()
 	expectedRecord := metriscache.Record{
-		SubAccountID: expectedSubAccIDToExist,
+		SubAccountID: uuid.New().String(),
+		RuntimeID:    expectedRuntimeIDToExist,
 		ShootName:    fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5)),
-		KubeConfig:   "foo",
 		Metric:       NewMetric(),
 	}
-	expectedSubAccIDWithNoMetrics := uuid.New().String()
+	expectedRuntimeIDWithNoMetrics := uuid.New().String()
 	recordsToBeAdded := []metriscache.Record{
 		expectedRecord,
 		{
 			SubAccountID: uuid.New().String(),
+			RuntimeID:    uuid.New().String(),
 			ShootName:    fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5)),
-			KubeConfig:   "foo",
 		},
 		{
-			SubAccountID: expectedSubAccIDWithNoMetrics,
+			SubAccountID: uuid.New().String(),
+			RuntimeID:    expectedRuntimeIDWithNoMetrics,
 			ShootName:    "",
-			KubeConfig:   "",
 		},
 	}
 	for _, record := range recordsToBeAdded {
-		err := cache.Add(record.SubAccountID, record, gocache.NoExpiration)
+		err := cache.Add(record.RuntimeID, record, gocache.NoExpiration)
 		g.Expect(err).Should(gomega.BeNil())
 	}
 
@@ -99,23 +104,23 @@ func TestGetOldRecordIfMetricExists(t *testing.T) {
 		Logger: logrus.New(),
 	}
 
-	t.Run("old metric found for a subAccountID", func(t *testing.T) {
-		gotRecord, err := p.getOldRecordIfMetricExists(expectedSubAccIDToExist)
+	t.Run("old metric found for a runtimeID", func(t *testing.T) {
+		gotRecord, err := p.getOldRecordIfMetricExists(expectedRuntimeIDToExist)
 		g.Expect(err).Should(gomega.BeNil())
 		g.Expect(*gotRecord).To(gomega.Equal(expectedRecord))
 	})
 
-	t.Run("old metric not found for a subAccountID", func(t *testing.T) {
-		subAccIDWhichDoesNotExist := uuid.New().String()
-		_, err := p.getOldRecordIfMetricExists(subAccIDWhichDoesNotExist)
+	t.Run("old metric not found for a runtimeID", func(t *testing.T) {
+		runtimeIDWhichDoesNotExist := uuid.New().String()
+		_, err := p.getOldRecordIfMetricExists(runtimeIDWhichDoesNotExist)
 		g.Expect(err).ShouldNot(gomega.BeNil())
-		g.Expect(err.Error()).To(gomega.Equal(fmt.Sprintf("subAccountID: %s not found", subAccIDWhichDoesNotExist)))
+		g.Expect(err.Error()).To(gomega.Equal(fmt.Sprintf("runtimeID: %s not found", runtimeIDWhichDoesNotExist)))
 	})
 
-	t.Run("old metric found for a subAccountID but does not have metric", func(t *testing.T) {
-		_, err := p.getOldRecordIfMetricExists(expectedSubAccIDWithNoMetrics)
+	t.Run("old metric found for a runtimeID but does not have metric", func(t *testing.T) {
+		_, err := p.getOldRecordIfMetricExists(expectedRuntimeIDWithNoMetrics)
 		g.Expect(err).ShouldNot(gomega.BeNil())
-		g.Expect(err.Error()).To(gomega.Equal(fmt.Sprintf("old metrics for subAccountID: %s not found", expectedSubAccIDWithNoMetrics)))
+		g.Expect(err.Error()).To(gomega.Equal(fmt.Sprintf("old metrics for runtimeID: %s not found", expectedRuntimeIDWithNoMetrics)))
 	})
 }
 
@@ -258,16 +263,16 @@ func TestPopulateCacheAndQueue(t *testing.T) {
 			Cache:  cache,
 			Logger: logrus.New(),
 		}
-		oldRecord := NewRecord(subAccID, oldShootName, "foo")
-		newRecord := NewRecord(subAccID, newShootName, "")
+		oldRecord := NewRecord(subAccID, oldShootName)
+		newRecord := NewRecord(subAccID, newShootName)
 
-		err := p.Cache.Add(subAccID, oldRecord, gocache.NoExpiration)
+		err := p.Cache.Add(oldRecord.RuntimeID, oldRecord, gocache.NoExpiration)
 		g.Expect(err).Should(gomega.BeNil())
 
 		runtimesPage := new(kebruntime.RuntimesPage)
 		expectedQueue := workqueue.NewDelayingQueue()
 		expectedCache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
-		err = expectedCache.Add(subAccID, newRecord, gocache.NoExpiration)
+		err = expectedCache.Add(newRecord.RuntimeID, newRecord, gocache.NoExpiration)
 		g.Expect(err).Should(gomega.BeNil())
 
 		runtime := metristesting.NewRuntimesDTO(subAccID, newShootName, metristesting.WithSucceededState)
@@ -278,6 +283,36 @@ func TestPopulateCacheAndQueue(t *testing.T) {
 		g.Expect(areQueuesEqual(p.Queue, expectedQueue)).To(gomega.BeTrue())
 	})
 
+	t.Run("with loaded cache but unsuspended with a new shoot", func(t *testing.T) {
+		subAccID := uuid.New().String()
+		oldShootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
+		newShootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
+
+		p := Process{
+			Queue:  workqueue.NewDelayingQueue(),
+			Cache:  gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+			Logger: logrus.New(),
+		}
+		oldRecord := NewRecord(subAccID, oldShootName)
+		oldRecord.Suspended = true
+		err := p.Cache.Add(oldRecord.RuntimeID, oldRecord, gocache.NoExpiration)
+		g.Expect(err).Should(gomega.BeNil())
+
+		// The unsuspension creates a new shoot, so both arrive in the same poll
+		runtime := metristesting.NewRuntimesDTO(subAccID, newShootName)
+		runtime.Status.Suspension.Data = []kebruntime.Operation{{State: "succeeded", CreatedAt: time.Now().Add(-time.Hour)}}
+		runtime.Status.Unsuspension.Data = []kebruntime.Operation{{State: "succeeded", CreatedAt: time.Now()}}
+		p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{runtime}})
+
+		obj, isFound := p.Cache.Get(oldRecord.RuntimeID)
+		g.Expect(isFound).To(gomega.BeTrue())
+		record := obj.(metriscache.Record)
+		g.Expect(record.ShootName).To(gomega.Equal(newShootName))
+		g.Expect(record.Suspended).To(gomega.BeFalse())
+		// The new shoot is scraped right away
+		g.Expect(p.Queue.Len()).To(gomega.Equal(1))
+	})
+
 	t.Run("with loaded cache but then shoot is deprovisioned", func(t *testing.T) {
 		subAccID := uuid.New().String()
 		cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
@@ -285,14 +320,31 @@ func TestPopulateCacheAndQueue(t *testing.T) {
 		oldShootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
 		newShootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
 
+		expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStream, testDataStreamVersion, subAccID, testEnv)
+		var mu sync.Mutex
+		var gotPayloads []string
+		edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			g.Expect(err).Should(gomega.BeNil())
+			mu.Lock()
+			gotPayloads = append(gotPayloads, string(body))
+			mu.Unlock()
+			rw.WriteHeader(http.StatusCreated)
+		})
+		srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+		defer srv.Close()
+		edpClient, err := edp.NewClient(newEDPConfig(srv.URL), logrus.New())
+		g.Expect(err).Should(gomega.BeNil())
+
 		p := Process{
-			Queue:  queue,
-			Cache:  cache,
-			Logger: logrus.New(),
+			EDPClient: edpClient,
+			Queue:     queue,
+			Cache:     cache,
+			Logger:    logrus.New(),
 		}
-		oldRecord := NewRecord(subAccID, oldShootName, "foo")
+		oldRecord := NewRecord(subAccID, oldShootName)
 
-		err := p.Cache.Add(subAccID, oldRecord, gocache.NoExpiration)
+		err = p.Cache.Add(oldRecord.RuntimeID, oldRecord, gocache.NoExpiration)
 		g.Expect(err).Should(gomega.BeNil())
 
 		runtimesPage := new(kebruntime.RuntimesPage)
@@ -305,23 +357,112 @@ func TestPopulateCacheAndQueue(t *testing.T) {
 		p.populateCacheAndQueue(runtimesPage)
 		g.Expect(*p.Cache).To(gomega.Equal(*expectedCache))
 		g.Expect(areQueuesEqual(p.Queue, expectedQueue)).To(gomega.BeTrue())
+
+		// A final event is sent for the deprovisioned runtime in the background
+		g.Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(gotPayloads)
+		}, timeout).Should(gomega.Equal(1))
+		g.Expect(gotPayloads[0]).To(gomega.ContainSubstring(`"deprovisioned":true`))
+		g.Expect(gotPayloads[0]).To(gomega.ContainSubstring(fmt.Sprintf(`"runtime_id":"%s"`, oldRecord.RuntimeID)))
+	})
+
+	t.Run("subaccount with multiple runtimes", func(t *testing.T) {
+		subAccID := uuid.New().String()
+		p := Process{
+			Queue:  workqueue.NewDelayingQueue(),
+			Cache:  gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+			Logger: logrus.New(),
+		}
+
+		runtimesPage := new(kebruntime.RuntimesPage)
+		expectedQueue := workqueue.NewDelayingQueue()
+		expectedCache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
+		for i := 0; i < 2; i++ {
+			runtime := metristesting.NewRuntimesDTO(subAccID, fmt.Sprintf("shoot-%d", i), metristesting.WithSucceededState)
+			runtime.RuntimeID = fmt.Sprintf("runtime-%d", i)
+			runtimesPage.Data = append(runtimesPage.Data, runtime)
+			err := expectedCache.Add(runtime.RuntimeID, metriscache.Record{
+				SubAccountID: subAccID,
+				RuntimeID:    runtime.RuntimeID,
+				ShootName:    runtime.ShootName,
+			}, gocache.NoExpiration)
+			g.Expect(err).Should(gomega.BeNil())
+			expectedQueue.Add(runtime.RuntimeID)
+		}
+
+		// Both runtimes are tracked and keep their shoot on the next poll
+		p.populateCacheAndQueue(runtimesPage)
+		p.populateCacheAndQueue(runtimesPage)
+		g.Expect(*p.Cache).To(gomega.Equal(*expectedCache))
+		g.Expect(areQueuesEqual(p.Queue, expectedQueue)).To(gomega.BeTrue())
+	})
+
+	t.Run("with loaded cache but plan changed", func(t *testing.T) {
+		subAccID := uuid.New().String()
+		shootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
+		p := Process{
+			Queue:  workqueue.NewDelayingQueue(),
+			Cache:  gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+			Logger: logrus.New(),
+		}
+		oldRecord := NewRecord(subAccID, shootName)
+		oldRecord.Metric = NewMetric()
+		err := p.Cache.Add(oldRecord.RuntimeID, oldRecord, gocache.NoExpiration)
+		g.Expect(err).Should(gomega.BeNil())
+
+		runtime := metristesting.NewRuntimesDTO(subAccID, shootName, func(runtime *kebruntime.RuntimeDTO) {
+			runtime.GlobalAccountID = "globalAccount"
+			runtime.ServicePlanName = "azure_lite"
+			runtime.ProviderRegion = "westeurope"
+		})
+		p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{runtime}})
+
+		// The metadata is updated while the last metric is kept
+		obj, isFound := p.Cache.Get(oldRecord.RuntimeID)
+		g.Expect(isFound).To(gomega.BeTrue())
+		record := obj.(metriscache.Record)
+		g.Expect(record.Runtime).To(gomega.Equal(metriscache.RuntimeMetadata{
+			GlobalAccountID: "globalAccount",
+			ServicePlanName: "azure_lite",
+			Region:          "westeurope",
+		}))
+		g.Expect(record.Metric).To(gomega.Equal(oldRecord.Metric))
+		g.Expect(p.Queue.Len()).To(gomega.BeZero())
 	})
 }
 
 func TestExecute(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	subAccID := uuid.New().String()
+	runtimeID := uuid.New().String()
 	tenant := subAccID
 	expectedKubeconfig := "eyJmb28iOiAiYmFyIn0="
 	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStream, testDataStreamVersion, tenant, testEnv)
 	log := logrus.New()
+	shootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
 
 	timesVisited := 0
 	// Set up EDP Test Server handler
 	expectedHeaders := expectedHeadersInEDPReq()
 	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		timesVisited += 1
-		g.Expect(req.Header).To(gomega.Equal(expectedHeaders))
+		// The event ID is sent along so that EDP can drop the resent snapshots
+		metric := edp.ConsumptionMetrics{}
+		g.Expect(json.NewDecoder(req.Body).Decode(&metric)).Should(gomega.BeNil())
+		g.Expect(metric.EventID).To(gomega.Equal(edp.NewConsumptionEventID(tenant, edp.VariantSnapshot, metric)))
+		g.Expect(req.Header.Get(edp.EventIDHeader)).To(gomega.Equal(metric.EventID))
+		// The runtime is identified in the payload
+		g.Expect(metric.RuntimeID).To(gomega.Equal(runtimeID))
+		g.Expect(metric.GlobalAccountID).To(gomega.Equal("globalAccount"))
+		g.Expect(metric.ShootName).To(gomega.Equal(shootName))
+		g.Expect(metric.ServicePlanName).To(gomega.Equal("azure"))
+		g.Expect(metric.Region).To(gomega.Equal("westeurope"))
+		g.Expect(metric.ProviderType).To(gomega.Equal("azure"))
+		headers := req.Header.Clone()
+		headers.Del(edp.EventIDHeader)
+		g.Expect(headers).To(gomega.Equal(expectedHeaders))
 		g.Expect(req.URL.Path).To(gomega.Equal(expectedPath))
 		g.Expect(req.Method).To(gomega.Equal(http.MethodPost))
 		rw.WriteHeader(http.StatusCreated)
@@ -331,34 +472,39 @@ func TestExecute(t *testing.T) {
 	defer srv.Close()
 
 	edpConfig := newEDPConfig(srv.URL)
-	edpClient := edp.NewClient(edpConfig, log)
-	shootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
+	edpClient, err := edp.NewClient(edpConfig, log)
+	g.Expect(err).Should(gomega.BeNil())
 	secret := metristesting.NewSecret(shootName, expectedKubeconfig)
 
 	// Populate cache
 	cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
 	newRecord := metriscache.Record{
 		SubAccountID: subAccID,
+		RuntimeID:    runtimeID,
 		ShootName:    shootName,
-		KubeConfig:   "",
-		Metric:       nil,
+		Runtime: metriscache.RuntimeMetadata{
+			GlobalAccountID: "globalAccount",
+			ServicePlanName: "azure",
+			Region:          "westeurope",
+		},
+		Metric: nil,
 	}
 	expectedRecord := newRecord
-	expectedRecord.KubeConfig = expectedKubeconfig
 	expectedRecord.Metric = NewMetric()
 
-	err := cache.Add(subAccID, newRecord, gocache.NoExpiration)
+	err = cache.Add(runtimeID, newRecord, gocache.NoExpiration)
 	g.Expect(err).Should(gomega.BeNil())
 
 	// Populate queue
 	queue := workqueue.NewDelayingQueue()
-	queue.Add(subAccID)
+	queue.Add(runtimeID)
 
 	shoot := metristesting.GetShoot(shootName, metristesting.WithAzureProviderAndStandardD8V3VMs)
 	shootClient, err := NewFakeShootClient(shoot)
 	g.Expect(err).Should(gomega.BeNil())
 	secretClient, err := NewFakeSecretClient(secret)
 	g.Expect(err).Should(gomega.BeNil())
+	kubeconfigManager := gardenerkubeconfig.NewManager(secretClient, log)
 
 	providersData, err := metristesting.LoadFixtureFromFile(providersFile)
 	g.Expect(err).Should(gomega.BeNil())
@@ -367,20 +513,23 @@ func TestExecute(t *testing.T) {
 	g.Expect(err).Should(gomega.BeNil())
 	fakeNodeClient := skrnode.FakeNodeClient{}
 	fakePVCClient := skrpvc.FakePVCClient{}
+	fakePVClient := skrpv.FakePVClient{}
 	fakeSvcClient := skrsvc.FakeSvcClient{}
 
 	newProcess := &Process{
-		EDPClient:      edpClient,
-		Queue:          queue,
-		ShootClient:    shootClient,
-		SecretClient:   secretClient,
-		Cache:          cache,
-		Providers:      providers,
-		ScrapeInterval: 3 * time.Second,
-		Logger:         log,
-		NodeConfig:     fakeNodeClient,
-		PVCConfig:      fakePVCClient,
-		SvcConfig:      fakeSvcClient,
+		EDPClient:         edpClient,
+		Queue:             queue,
+		ShootClient:       shootClient,
+		KubeconfigManager: kubeconfigManager,
+		Cache:             cache,
+		Statuses:          metriscache.NewStatusStore(),
+		Providers:         providers,
+		ScrapeInterval:    3 * time.Second,
+		Logger:            log,
+		NodeConfig:        fakeNodeClient,
+		PVCConfig:         fakePVCClient,
+		PVConfig:          fakePVClient,
+		SvcConfig:         fakeSvcClient,
 	}
 
 	go func() {
@@ -395,15 +544,16 @@ func TestExecute(t *testing.T) {
 	// Test cache state
 	g.Eventually(newProcess.Cache.ItemCount(), timeout).Should(gomega.Equal(1))
 	g.Eventually(func() error {
-		gotItemFromCache, found := newProcess.Cache.Get(subAccID)
+		gotItemFromCache, found := newProcess.Cache.Get(runtimeID)
 		if !found {
-			return fmt.Errorf("subAccID not found in cache")
+			return fmt.Errorf("runtimeID not found in cache")
 		}
 		record, ok := gotItemFromCache.(metriscache.Record)
 		g.Expect(ok).To(gomega.BeTrue())
-		if record.KubeConfig != expectedRecord.KubeConfig {
-			return fmt.Errorf("kubeconfigs mismatch, got: %s,expected: %s", record.KubeConfig, expectedRecord.KubeConfig)
+		if record.Metric == nil {
+			return fmt.Errorf("metric not found for runtimeID")
 		}
+		g.Expect(record.Metric.RuntimeID).To(gomega.Equal(runtimeID))
 		if !reflect.DeepEqual(record.Metric.Networking, expectedRecord.Metric.Networking) {
 			g.Expect(record.Metric.Networking).To(gomega.Equal(expectedRecord.Metric.Networking))
 			return fmt.Errorf("networking data mismatch, got: %v, expected: %v", record.Metric.Networking, expectedRecord.Metric.Networking)
@@ -415,15 +565,84 @@ func TestExecute(t *testing.T) {
 		return nil
 	}, bigTimeout).Should(gomega.BeNil())
 
+	// Test scrape status
+	g.Eventually(func() int {
+		status, _ := newProcess.Statuses.Get(runtimeID)
+		return status.LastEDPStatusCode
+	}, timeout).Should(gomega.Equal(http.StatusCreated))
+	gotStatuses := newProcess.ScrapeStatuses()
+	g.Expect(gotStatuses).To(gomega.HaveLen(1))
+	g.Expect(gotStatuses[0].SubAccountID).To(gomega.Equal(subAccID))
+	g.Expect(gotStatuses[0].RuntimeID).To(gomega.Equal(runtimeID))
+	g.Expect(gotStatuses[0].ShootName).To(gomega.Equal(shootName))
+	g.Expect(gotStatuses[0].LastSuccessfulScrape).ShouldNot(gomega.BeNil())
+	g.Expect(gotStatuses[0].LastSent).ShouldNot(gomega.BeNil())
+	g.Expect(gotStatuses[0].NextScrape).ShouldNot(gomega.BeNil())
+	g.Expect(gotStatuses[0].LastErrorStage).To(gomega.BeEmpty())
+
 	// Test queue state
 	g.Eventually(func() string {
 		item, _ := newProcess.Queue.Get()
-		subAccountID := fmt.Sprintf("%v", item)
-		return subAccountID
-	}, timeout).Should(gomega.Equal(subAccID))
+		return fmt.Sprintf("%v", item)
+	}, timeout).Should(gomega.Equal(runtimeID))
 
 }
 
+func TestScrapeStatusOnFailure(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+	shootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
+
+	// The kubeconfig secret of another shoot only
+	secretClient, err := NewFakeSecretClient(metristesting.NewSecret("other-shoot", "foo"))
+	g.Expect(err).Should(gomega.BeNil())
+
+	cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
+	oldRecord := NewRecord(subAccID, shootName)
+	oldRecord.Metric = NewMetric()
+	runtimeID := oldRecord.RuntimeID
+	err = cache.Add(runtimeID, oldRecord, gocache.NoExpiration)
+	g.Expect(err).Should(gomega.BeNil())
+
+	shootClient, err := NewFakeShootClient(metristesting.GetShoot(shootName, metristesting.WithAzureProviderAndStandardD8V3VMs))
+	g.Expect(err).Should(gomega.BeNil())
+
+	p := Process{
+		Queue:             workqueue.NewDelayingQueue(),
+		ShootClient:       shootClient,
+		KubeconfigManager: gardenerkubeconfig.NewManager(secretClient, log),
+		Cache:             cache,
+		Statuses:          metriscache.NewStatusStore(),
+		Logger:            log,
+	}
+
+	// Falls back to the old metric
+	gotRecord, isOldMetricValid, err := p.getRecordWithOldOrNewMetric(1, runtimeID)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(isOldMetricValid).To(gomega.BeTrue())
+	g.Expect(*gotRecord).To(gomega.Equal(oldRecord))
+
+	p.recordSent(runtimeID, http.StatusCreated, isOldMetricValid, nil)
+	gotStatuses := p.ScrapeStatuses()
+	g.Expect(gotStatuses).To(gomega.HaveLen(1))
+	g.Expect(gotStatuses[0].SubAccountID).To(gomega.Equal(subAccID))
+	g.Expect(gotStatuses[0].ShootName).To(gomega.Equal(shootName))
+	g.Expect(gotStatuses[0].LastErrorStage).To(gomega.Equal(stageKubeconfig))
+	g.Expect(gotStatuses[0].LastError).To(gomega.ContainSubstring("failed to get kubeconfig secret"))
+	g.Expect(gotStatuses[0].LastSuccessfulScrape).Should(gomega.BeNil())
+	g.Expect(gotStatuses[0].LastPayloadStale).To(gomega.BeTrue())
+	g.Expect(gotStatuses[0].LastEDPStatusCode).To(gomega.Equal(http.StatusCreated))
+
+	// Failed sends keep the last successful send
+	p.recordSent(runtimeID, http.StatusInternalServerError, false, edp.ResponseError{StatusCode: http.StatusInternalServerError})
+	status, found := p.Statuses.Get(runtimeID)
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(status.LastErrorStage).To(gomega.Equal(stageEDP))
+	g.Expect(status.LastEDPStatusCode).To(gomega.Equal(http.StatusInternalServerError))
+	g.Expect(status.LastPayloadStale).To(gomega.BeTrue())
+}
+
 func NewFakeShootClient(shoot *gardenerv1beta1.Shoot) (*gardenershoot.Client, error) {
 	scheme, err := commons.SetupSchemeOrDie()
 	if err != nil {
@@ -460,11 +679,11 @@ func NewFakeSecretClient(secret *corev1.Secret) (*gardenersecret.Client, error)
 	return &gardenersecret.Client{ResourceClient: nsResourceClient}, nil
 }
 
-func NewRecord(subAccId, shootName, kubeconfig string) metriscache.Record {
+func NewRecord(subAccId, shootName string) metriscache.Record {
 	return metriscache.Record{
 		SubAccountID: subAccId,
+		RuntimeID:    metristesting.NewRuntimeID(subAccId),
 		ShootName:    shootName,
-		KubeConfig:   kubeconfig,
 		Metric:       nil,
 	}
 }
@@ -489,28 +708,30 @@ func AddSuccessfulIDsToCacheQueueAndRuntimes(runtimesPage *kebruntime.RuntimesPa
 		shootName := fmt.Sprintf("shoot-%s", shootID)
 		runtime := metristesting.NewRuntimesDTO(successfulID, shootName, metristesting.WithSucceededState)
 		runtimesPage.Data = append(runtimesPage.Data, runtime)
-		err := expectedCache.Add(successfulID, metriscache.Record{
+		err := expectedCache.Add(runtime.RuntimeID, metriscache.Record{
 			SubAccountID: successfulID,
+			RuntimeID:    runtime.RuntimeID,
 			ShootName:    shootName,
 		}, gocache.NoExpiration)
 		if err != nil {
 			return nil, nil, nil, err
 		}
-		expectedQueue.Add(successfulID)
+		expectedQueue.Add(runtime.RuntimeID)
 	}
 	return runtimesPage, expectedCache, expectedQueue, nil
 }
 
 func newEDPConfig(url string) *edp.Config {
 	return &edp.Config{
-		URL:               url,
-		Token:             testToken,
-		Namespace:         testNamespace,
-		DataStreamName:    testDataStream,
-		DataStreamVersion: testDataStreamVersion,
-		DataStreamEnv:     testEnv,
-		Timeout:           timeout,
-		EventRetry:        retryCount,
+		URL:                     url,
+		Token:                   testToken,
+		Namespace:               testNamespace,
+		DataStreamName:          testDataStream,
+		DataStreamVersion:       testDataStreamVersion,
+		DataStreamEnv:           testEnv,
+		LifecycleDataStreamName: testLifecycleStream,
+		Timeout:                 timeout,
+		EventRetry:              retryCount,
 	}
 }
 
@@ -525,7 +746,7 @@ func expectedHeadersInEDPReq() http.Header {
 
 func NewMetric() *edp.ConsumptionMetrics {
 	return &edp.ConsumptionMetrics{
-		Timestamp: "",
+		Timestamp: "2021-02-01T10:00:00Z",
 		Compute: edp.Compute{
 			VMTypes: []edp.VMType{
 				{
@@ -536,14 +757,30 @@ func NewMetric() *edp.ConsumptionMetrics {
 			ProvisionedCpus:  24,
 			ProvisionedRAMGb: 96,
 			ProvisionedVolumes: edp.ProvisionedVolumes{
-				SizeGbTotal:   630,
-				Count:         5,
+				SizeGbTotal:   635,
+				Count:         6,
 				SizeGbRounded: 640,
 			},
+			WorkerPools: []edp.WorkerPool{{
+				Name:        "cpu-worker-0",
+				MachineType: "Standard_D8_v3",
+				Nodes:       3,
+				Minimum:     2,
+				Maximum:     5,
+				Zones:       []string{"1", "2"},
+				System:      true,
+			}},
+			NodeVolumes: []edp.VolumeType{{Type: "default", Count: 3, SizeGbTotal: 600}},
+			StorageClasses: []edp.StorageClass{
+				{Count: 1, SizeGb: 20},
+				{Name: "default", Provisioner: "disk.csi.azure.com", DiskType: "StandardSSD_LRS", Count: 2, SizeGb: 15, Released: 1},
+			},
 		},
 		Networking: edp.Networking{
-			ProvisionedVnets: 1,
-			ProvisionedIPs:   2,
+			ProvisionedVnets:      1,
+			ProvisionedIPs:        2,
+			PublicLoadBalancers:   2,
+			PublicLoadBalancerIPs: 2,
 		},
 	}
 }
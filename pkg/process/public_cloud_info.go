@@ -26,6 +26,8 @@ type Feature struct {
 	Memory   float64 `json:"memory"`
 	Storage  int64   `json:"storage"`
 	MaxNICs  int     `json:"max_nics"`
+	GPUs     int     `json:"gpus"`
+	GPUType  string  `json:"gpu_type"`
 }
 
 func (p Providers) GetFeatures(cloudProvider, vmType string) (f *Features) {
@@ -0,0 +1,179 @@
+package process
+
+import (
+	"time"
+
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+	"github.com/pkg/errors"
+)
+
+// reconcileCache evicts the runtimes which are missing in the complete listing of KEB for longer than
+// the grace period. KEB might briefly not list a runtime, e.g. while it is migrated, hence they are not
+// evicted right away. A missing runtime which KEB still knows is filtered out and stops being tracked silently.
+func (p *Process) reconcileCache(runtimes *kebruntime.RuntimesPage) {
+	if p.missingSince == nil {
+		p.missingSince = make(map[string]time.Time)
+	}
+
+	listed := make(map[string]bool, len(runtimes.Data))
+	for _, runtime := range runtimes.Data {
+		listed[runtime.RuntimeID] = true
+	}
+
+	now := time.Now()
+	for runtimeID, item := range p.Cache.Items() {
+		if listed[runtimeID] {
+			delete(p.missingSince, runtimeID)
+			continue
+		}
+		since, isMissing := p.missingSince[runtimeID]
+		if !isMissing {
+			p.missingSince[runtimeID] = now
+			p.Logger.Infof("runtimeID: %s is not listed by KEB anymore, evicting it after %v", runtimeID, p.RuntimeGracePeriod)
+			continue
+		}
+		if now.Sub(since) >= p.RuntimeGracePeriod {
+			isDeprovisioned, deprovisionedAt, err := p.isDeprovisioned(runtimeID)
+			if err != nil {
+				p.Logger.Errorf("failed to look up missing runtimeID: %s in KEB, retrying with the next poll: %v", runtimeID, err)
+				continue
+			}
+			if !isDeprovisioned {
+				p.untrack(runtimeID)
+				continue
+			}
+			if deprovisionedAt.IsZero() {
+				deprovisionedAt = since
+			}
+			if record, ok := item.Object.(metriscache.Record); ok {
+				p.forgetLifecycle(record.SubAccountID, runtimeID, deprovisionedAt)
+			}
+			p.evict(runtimeID)
+		}
+	}
+
+	// Forget the runtimes which were evicted in the meantime
+	for runtimeID := range p.missingSince {
+		if !p.isTracked(runtimeID) {
+			delete(p.missingSince, runtimeID)
+		}
+	}
+	for runtimeID, state := range p.runtimeStates {
+		if listed[runtimeID] || p.isTracked(runtimeID) {
+			continue
+		}
+		if !state.Provisioned || state.Deprovisioned {
+			delete(p.runtimeStates, runtimeID)
+			continue
+		}
+		// The runtime went missing while it was not tracked, e.g. while Metris was restarted
+		isDeprovisioned, deprovisionedAt, err := p.isDeprovisioned(runtimeID)
+		if err != nil {
+			p.Logger.Errorf("failed to look up missing runtimeID: %s in KEB, retrying with the next poll: %v", runtimeID, err)
+			continue
+		}
+		if !isDeprovisioned {
+			delete(p.runtimeStates, runtimeID)
+			continue
+		}
+		if deprovisionedAt.IsZero() {
+			deprovisionedAt = now
+		}
+		p.forgetLifecycle(state.SubAccountID, runtimeID, deprovisionedAt)
+	}
+}
+
+// isDeprovisioned looks up a runtime which is missing in the listing of KEB. A runtime which KEB does not know
+// anymore or which is being deprovisioned is gone, any other runtime is only filtered out. The creation time of
+// the deprovisioning operation is returned if KEB still knows it.
+func (p Process) isDeprovisioned(runtimeID string) (bool, time.Time, error) {
+	kebReq, err := p.KEBClient.NewRequest()
+	if err != nil {
+		return false, time.Time{}, errors.Wrapf(err, "failed to create a new request for KEB")
+	}
+	runtime, err := p.KEBClient.GetRuntime(kebReq, runtimeID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if runtime == nil {
+		return true, time.Time{}, nil
+	}
+	if runtime.Status.Deprovisioning != nil {
+		return true, runtime.Status.Deprovisioning.CreatedAt, nil
+	}
+	return false, time.Time{}, nil
+}
+
+// untrackExcluded stops tracking the runtimes which are listed by KEB but excluded by the filter
+func (p *Process) untrackExcluded(runtimeIDs []string) {
+	for _, runtimeID := range runtimeIDs {
+		if _, isKnown := p.runtimeStates[runtimeID]; isKnown || p.isTracked(runtimeID) {
+			p.untrack(runtimeID)
+		}
+	}
+}
+
+// untrack stops tracking a runtime without any event, as it still exists but is filtered out
+func (p *Process) untrack(runtimeID string) {
+	if obj, isFound := p.Cache.Get(runtimeID); isFound {
+		if record, ok := obj.(metriscache.Record); ok {
+			p.KubeconfigManager.Invalidate(record.ShootName)
+		}
+	}
+	p.Cache.Delete(runtimeID)
+	p.Statuses.Delete(runtimeID)
+	delete(p.missingSince, runtimeID)
+	delete(p.runtimeStates, runtimeID)
+	p.Aggregator.Forget(runtimeID, time.Now())
+	p.Logger.Infof("stopped tracking runtimeID: %s", runtimeID)
+}
+
+// evict stops tracking a runtime and sends a final event so that EDP can close the billing period
+func (p *Process) evict(runtimeID string) {
+	obj, isFound := p.Cache.Get(runtimeID)
+	p.Cache.Delete(runtimeID)
+	p.Statuses.Delete(runtimeID)
+	delete(p.missingSince, runtimeID)
+	p.Aggregator.Forget(runtimeID, time.Now())
+	p.Logger.Infof("stopped tracking runtimeID: %s as it is deprovisioned", runtimeID)
+
+	record, ok := obj.(metriscache.Record)
+	if !isFound || !ok {
+		return
+	}
+	p.KubeconfigManager.Invalidate(record.ShootName)
+	if err := p.sendDeprovisionedEvent(record); err != nil {
+		p.Logger.Errorf("failed to send deprovisioned event for subAccountID: %s, runtimeID: %s: %v", record.SubAccountID, runtimeID, err)
+	}
+}
+
+// sendDeprovisionedEvent sends an event without any consumption which marks the runtime as deprovisioned.
+// It is sent in the background so that a slow EDP does not hold up the polling of KEB.
+func (p Process) sendDeprovisionedEvent(record metriscache.Record) error {
+	subAccountID := record.SubAccountID
+	timestamp := getTimestampNow(p.ScrapeInterval)
+	metric := edp.ConsumptionMetrics{
+		Timestamp:     timestamp,
+		Compute:       edp.Compute{VMTypes: []edp.VMType{}},
+		Deprovisioned: true,
+	}
+	setRuntimeMetadata(&metric, record)
+	metric.EventID = edp.NewConsumptionEventID(subAccountID, edp.VariantDeprovisioned, metric)
+	payload, err := edp.EncodeConsumptionMetrics(metric, edp.LatestConsumptionSchema)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode deprovisioned event")
+	}
+	if err := p.deliverAsync("", subAccountID, payload); err != nil {
+		return err
+	}
+	p.Logger.Infof("queued deprovisioned event for subAccountID: %s, runtimeID: %s", subAccountID, record.RuntimeID)
+	return nil
+}
+
+// isTracked checks if the runtime is still in the cache
+func (p Process) isTracked(runtimeID string) bool {
+	_, isFound := p.Cache.Get(runtimeID)
+	return isFound
+}
@@ -0,0 +1,243 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	gardenerkubeconfig "github.com/kyma-incubator/metris/pkg/gardener/kubeconfig"
+	metriskeb "github.com/kyma-incubator/metris/pkg/keb"
+	"github.com/kyma-incubator/metris/pkg/outbox"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+	"github.com/onsi/gomega"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestReconcileCache(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	listedSubAccID := uuid.New().String()
+	missingSubAccID := uuid.New().String()
+	listedRuntimeID := metristesting.NewRuntimeID(listedSubAccID)
+	missingRuntimeID := metristesting.NewRuntimeID(missingSubAccID)
+
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStream, testDataStreamVersion, missingSubAccID, testEnv)
+	var mu sync.Mutex
+	var gotMetrics []edp.ConsumptionMetrics
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		metric := edp.ConsumptionMetrics{}
+		err := json.NewDecoder(req.Body).Decode(&metric)
+		g.Expect(err).Should(gomega.BeNil())
+		mu.Lock()
+		gotMetrics = append(gotMetrics, metric)
+		mu.Unlock()
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), log)
+	g.Expect(err).Should(gomega.BeNil())
+	kebClient, kebSrv := newFakeKEBClient(g)
+	defer kebSrv.Close()
+	p := Process{
+		KEBClient:          kebClient,
+		EDPClient:          edpClient,
+		Queue:              workqueue.NewDelayingQueue(),
+		Cache:              gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+		Statuses:           metriscache.NewStatusStore(),
+		RuntimeGracePeriod: time.Hour,
+		Logger:             log,
+	}
+	for _, subAccID := range []string{listedSubAccID, missingSubAccID} {
+		err := p.Cache.Add(metristesting.NewRuntimeID(subAccID), NewRecord(subAccID, "shoot"), gocache.NoExpiration)
+		g.Expect(err).Should(gomega.BeNil())
+	}
+	runtimesPage := &kebruntime.RuntimesPage{
+		Data: []kebruntime.RuntimeDTO{metristesting.NewRuntimesDTO(listedSubAccID, "shoot")},
+	}
+
+	p.recordScrapeSuccess(missingRuntimeID)
+
+	// The missing subaccount is kept during the grace period
+	p.reconcileCache(runtimesPage)
+	g.Expect(p.isTracked(missingRuntimeID)).To(gomega.BeTrue())
+	g.Expect(p.missingSince).To(gomega.HaveKey(missingRuntimeID))
+	g.Expect(gotMetrics).To(gomega.BeEmpty())
+
+	// Once the grace period is over, it is evicted with a final event
+	p.missingSince[missingRuntimeID] = time.Now().Add(-2 * time.Hour)
+	p.reconcileCache(runtimesPage)
+	g.Expect(p.isTracked(missingRuntimeID)).To(gomega.BeFalse())
+	g.Expect(p.isTracked(listedRuntimeID)).To(gomega.BeTrue())
+	g.Expect(p.missingSince).To(gomega.BeEmpty())
+	// The final event is sent in the background
+	g.Eventually(func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(gotMetrics)
+	}, timeout).Should(gomega.Equal(1))
+	g.Expect(gotMetrics[0].Deprovisioned).To(gomega.BeTrue())
+	g.Expect(gotMetrics[0].RuntimeID).To(gomega.Equal(missingRuntimeID))
+	g.Expect(gotMetrics[0].Compute.ProvisionedCpus).To(gomega.Equal(0))
+	g.Expect(gotMetrics[0].Timestamp).ShouldNot(gomega.BeEmpty())
+
+	// The status of the evicted runtime is gone and a worker which is still busy with it does not bring it back
+	_, found := p.Statuses.Get(missingRuntimeID)
+	g.Expect(found).To(gomega.BeFalse())
+	p.recordNextScrape(missingRuntimeID, time.Now())
+	_, found = p.Statuses.Get(missingRuntimeID)
+	g.Expect(found).To(gomega.BeFalse())
+
+	// A subaccount which shows up again is not evicted
+	err = p.Cache.Add(missingRuntimeID, NewRecord(missingSubAccID, "shoot"), gocache.NoExpiration)
+	g.Expect(err).Should(gomega.BeNil())
+	p.reconcileCache(runtimesPage)
+	g.Expect(p.missingSince).To(gomega.HaveKey(missingRuntimeID))
+	runtimesPage.Data = append(runtimesPage.Data, metristesting.NewRuntimesDTO(missingSubAccID, "shoot"))
+	p.reconcileCache(runtimesPage)
+	g.Expect(p.missingSince).To(gomega.BeEmpty())
+	g.Expect(p.isTracked(missingRuntimeID)).To(gomega.BeTrue())
+}
+
+func TestReconcileCacheWithFilteredRuntimes(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+	excludedRuntime := metristesting.NewRuntimesDTO(subAccID, "excluded-shoot")
+	excludedRuntime.RuntimeID = uuid.New().String()
+	filteredRuntime := metristesting.NewRuntimesDTO(subAccID, "filtered-shoot")
+	filteredRuntime.RuntimeID = uuid.New().String()
+
+	// No event must reach EDP on any datastream
+	var gotPaths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotPaths = append(gotPaths, req.URL.Path)
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), log)
+	g.Expect(err).Should(gomega.BeNil())
+	// KEB still knows the filtered runtime
+	kebClient, kebSrv := newFakeKEBClient(g, filteredRuntime)
+	defer kebSrv.Close()
+	p := Process{
+		KEBClient:          kebClient,
+		EDPClient:          edpClient,
+		Queue:              workqueue.NewDelayingQueue(),
+		Cache:              gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+		Statuses:           metriscache.NewStatusStore(),
+		RuntimeGracePeriod: time.Hour,
+		Logger:             log,
+	}
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{excludedRuntime, filteredRuntime}})
+	g.Expect(p.Cache.ItemCount()).To(gomega.Equal(2))
+
+	// A runtime which is excluded by the filter of metris stops being tracked right away
+	p.untrackExcluded([]string{excludedRuntime.RuntimeID})
+	g.Expect(p.isTracked(excludedRuntime.RuntimeID)).To(gomega.BeFalse())
+	g.Expect(p.runtimeStates).ToNot(gomega.HaveKey(excludedRuntime.RuntimeID))
+
+	// A runtime which is filtered out by KEB stops being tracked after the grace period
+	runtimesPage := &kebruntime.RuntimesPage{}
+	p.reconcileCache(runtimesPage)
+	p.missingSince[filteredRuntime.RuntimeID] = time.Now().Add(-2 * time.Hour)
+	p.reconcileCache(runtimesPage)
+	g.Expect(p.isTracked(filteredRuntime.RuntimeID)).To(gomega.BeFalse())
+	g.Expect(p.runtimeStates).ToNot(gomega.HaveKey(filteredRuntime.RuntimeID))
+	g.Expect(p.missingSince).To(gomega.BeEmpty())
+	g.Expect(gotPaths).To(gomega.BeEmpty())
+}
+
+func TestInvalidateKubeconfigOfUntrackedShoots(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	log := logrus.New()
+	subAccID := uuid.New().String()
+
+	dir, err := ioutil.TempDir("", "outbox")
+	g.Expect(err).Should(gomega.BeNil())
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	edpOutbox, err := outbox.New(dir, 10, time.Minute, log)
+	g.Expect(err).Should(gomega.BeNil())
+
+	// cacheKubeconfig caches the kubeconfig of a shoot and deletes its secret, so that it can only be got from the cache
+	cacheKubeconfig := func(shootName string) *gardenerkubeconfig.Manager {
+		secret := metristesting.NewSecret(shootName, "foo")
+		secretClient, err := NewFakeSecretClient(secret)
+		g.Expect(err).Should(gomega.BeNil())
+		manager := gardenerkubeconfig.NewManager(secretClient, log)
+		_, err = manager.Get(ctx, shootName)
+		g.Expect(err).Should(gomega.BeNil())
+		err = secretClient.ResourceClient.Delete(ctx, secret.Name, metaV1.DeleteOptions{})
+		g.Expect(err).Should(gomega.BeNil())
+		_, err = manager.Get(ctx, shootName)
+		g.Expect(err).Should(gomega.BeNil())
+		return manager
+	}
+	p := Process{
+		KubeconfigManager:  cacheKubeconfig("old-shoot"),
+		Outbox:             edpOutbox,
+		Queue:              workqueue.NewDelayingQueue(),
+		Cache:              gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+		Statuses:           metriscache.NewStatusStore(),
+		RuntimeGracePeriod: time.Hour,
+		Logger:             log,
+	}
+	err = p.Cache.Add(metristesting.NewRuntimeID(subAccID), NewRecord(subAccID, "old-shoot"), gocache.NoExpiration)
+	g.Expect(err).Should(gomega.BeNil())
+
+	// The kubeconfig of the old shoot is dropped when the runtime gets a new shoot
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{metristesting.NewRuntimesDTO(subAccID, "new-shoot")}})
+	_, err = p.KubeconfigManager.Get(ctx, "old-shoot")
+	g.Expect(err).ShouldNot(gomega.BeNil())
+
+	// The kubeconfig of an evicted runtime is dropped
+	p.KubeconfigManager = cacheKubeconfig("new-shoot")
+	p.evict(metristesting.NewRuntimeID(subAccID))
+	_, err = p.KubeconfigManager.Get(ctx, "new-shoot")
+	g.Expect(err).ShouldNot(gomega.BeNil())
+}
+
+// newFakeKEBClient creates a KEB client which looks up the runtimes by their ID
+func newFakeKEBClient(g *gomega.WithT, runtimes ...kebruntime.RuntimeDTO) (*metriskeb.Client, *httptest.Server) {
+	getRuntimesHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		runtimesPage := kebruntime.RuntimesPage{}
+		for _, runtime := range runtimes {
+			if runtime.RuntimeID == req.URL.Query().Get(kebruntime.RuntimeIDParam) {
+				runtimesPage.Data = append(runtimesPage.Data, runtime)
+			}
+		}
+		runtimesPage.Count = len(runtimesPage.Data)
+		runtimesPage.TotalCount = len(runtimesPage.Data)
+		err := json.NewEncoder(rw).Encode(runtimesPage)
+		g.Expect(err).Should(gomega.BeNil())
+	})
+	srv := metristesting.StartTestServer(expectedPathPrefix, getRuntimesHandler, g)
+	kebClient := &metriskeb.Client{
+		HTTPClient: http.DefaultClient,
+		Logger:     logrus.New(),
+		Config: &metriskeb.Config{
+			URL:        fmt.Sprintf("%s%s", srv.URL, expectedPathPrefix),
+			RetryCount: 1,
+		},
+	}
+	return kebClient, srv
+}
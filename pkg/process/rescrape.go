@@ -0,0 +1,90 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	"github.com/pkg/errors"
+)
+
+// RuntimeForShoot returns the runtimeID and the subAccountID of the tracked runtime which owns the shoot
+func (p Process) RuntimeForShoot(shootName string) (string, string, bool) {
+	for runtimeID, item := range p.Cache.Items() {
+		if record, ok := item.Object.(metriscache.Record); ok && record.ShootName == shootName {
+			return runtimeID, record.SubAccountID, true
+		}
+	}
+	return "", "", false
+}
+
+// runtimeIDsForSubAccount returns the IDs of the tracked runtimes of a subaccount sorted by runtimeID
+func (p Process) runtimeIDsForSubAccount(subAccountID string) []string {
+	var runtimeIDs []string
+	for runtimeID, item := range p.Cache.Items() {
+		if record, ok := item.Object.(metriscache.Record); ok && record.SubAccountID == subAccountID {
+			runtimeIDs = append(runtimeIDs, runtimeID)
+		}
+	}
+	sort.Strings(runtimeIDs)
+	return runtimeIDs
+}
+
+// Rescrape queues all the runtimes of a subaccount to be scraped right away instead of waiting for the scrape interval.
+// It returns false if the subaccount is not tracked.
+func (p Process) Rescrape(subAccountID string) bool {
+	runtimeIDs := p.runtimeIDsForSubAccount(subAccountID)
+	for _, runtimeID := range runtimeIDs {
+		p.RescrapeRuntime(runtimeID)
+	}
+	return len(runtimeIDs) > 0
+}
+
+// RescrapeRuntime queues a single runtime to be scraped right away. It returns false if the runtime is not tracked.
+func (p Process) RescrapeRuntime(runtimeID string) bool {
+	if !p.isTracked(runtimeID) {
+		return false
+	}
+	p.Queue.Add(runtimeID)
+	p.Logger.Infof("queued runtimeID: %s to be rescraped", runtimeID)
+	return true
+}
+
+// Resend sends the latest metric of every runtime of a subaccount to EDP again and returns the HTTP status code
+// of EDP for the last one. It stops at the first runtime which fails.
+func (p Process) Resend(subAccountID string) (int, error) {
+	runtimeIDs := p.runtimeIDsForSubAccount(subAccountID)
+	if len(runtimeIDs) == 0 {
+		return 0, fmt.Errorf("subAccountID: %s not found", subAccountID)
+	}
+
+	statusCode := 0
+	for _, runtimeID := range runtimeIDs {
+		var err error
+		if statusCode, err = p.ResendRuntime(runtimeID); err != nil {
+			return statusCode, err
+		}
+	}
+	return statusCode, nil
+}
+
+// ResendRuntime sends the latest metric of a single runtime to EDP again and returns the HTTP status code of EDP
+func (p Process) ResendRuntime(runtimeID string) (int, error) {
+	record, err := p.getOldRecordIfMetricExists(runtimeID)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := edp.EncodeConsumptionMetrics(*record.Metric, edp.LatestConsumptionSchema)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to encode metric for runtimeID: %s", runtimeID)
+	}
+
+	statusCode, err := p.sendEventStreamToEDP("", record.SubAccountID, payload)
+	p.recordSent(runtimeID, statusCode, true, err)
+	if err != nil {
+		return statusCode, err
+	}
+	p.Logger.Infof("successfully resent event stream for subAccountID: %s, runtimeID: %s, shoot: %s", record.SubAccountID, runtimeID, record.ShootName)
+	return statusCode, nil
+}
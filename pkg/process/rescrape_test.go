@@ -0,0 +1,110 @@
+package process
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestRescrapeAndResend(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	log := logrus.New()
+	subAccID := uuid.New().String()
+	subAccIDWithoutMetric := uuid.New().String()
+	shootName := fmt.Sprintf("shoot-%s", metristesting.GenerateRandomAlphaString(5))
+
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testDataStream, testDataStreamVersion, subAccID, testEnv)
+	timesVisited := 0
+	edpTestHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		timesVisited += 1
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := metristesting.StartTestServer(expectedPath, edpTestHandler, g)
+	defer srv.Close()
+
+	cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
+	record := NewRecord(subAccID, shootName)
+	record.Metric = NewMetric()
+	err := cache.Add(record.RuntimeID, record, gocache.NoExpiration)
+	g.Expect(err).Should(gomega.BeNil())
+	err = cache.Add(metristesting.NewRuntimeID(subAccIDWithoutMetric), NewRecord(subAccIDWithoutMetric, "other-shoot"), gocache.NoExpiration)
+	g.Expect(err).Should(gomega.BeNil())
+
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), log)
+	g.Expect(err).Should(gomega.BeNil())
+	p := Process{
+		EDPClient: edpClient,
+		Queue:     workqueue.NewDelayingQueue(),
+		Cache:     cache,
+		Statuses:  metriscache.NewStatusStore(),
+		Logger:    log,
+	}
+
+	t.Run("find runtime by shoot", func(t *testing.T) {
+		gotRuntimeID, gotSubAccID, found := p.RuntimeForShoot(shootName)
+		g.Expect(found).To(gomega.BeTrue())
+		g.Expect(gotRuntimeID).To(gomega.Equal(record.RuntimeID))
+		g.Expect(gotSubAccID).To(gomega.Equal(subAccID))
+
+		_, _, found = p.RuntimeForShoot("doesnotexist")
+		g.Expect(found).To(gomega.BeFalse())
+	})
+
+	t.Run("rescrape a single runtime of a subaccount", func(t *testing.T) {
+		// Another runtime of the same subaccount is left alone
+		otherRecord := NewRecord(subAccID, "another-shoot")
+		otherRecord.RuntimeID = uuid.New().String()
+		g.Expect(p.Cache.Add(otherRecord.RuntimeID, otherRecord, gocache.NoExpiration)).Should(gomega.Succeed())
+		defer p.Cache.Delete(otherRecord.RuntimeID)
+
+		g.Expect(p.RescrapeRuntime(record.RuntimeID)).To(gomega.BeTrue())
+		g.Expect(p.Queue.Len()).To(gomega.Equal(1))
+		item, _ := p.Queue.Get()
+		g.Expect(item).To(gomega.Equal(record.RuntimeID))
+		p.Queue.Done(item)
+
+		g.Expect(p.RescrapeRuntime(uuid.New().String())).To(gomega.BeFalse())
+		g.Expect(p.Queue.Len()).To(gomega.Equal(0))
+	})
+
+	t.Run("rescrape queues the runtimes of the subaccount right away", func(t *testing.T) {
+		g.Expect(p.Rescrape(subAccID)).To(gomega.BeTrue())
+		g.Expect(p.Queue.Len()).To(gomega.Equal(1))
+		item, _ := p.Queue.Get()
+		g.Expect(item).To(gomega.Equal(record.RuntimeID))
+		p.Queue.Done(item)
+
+		g.Expect(p.Rescrape(uuid.New().String())).To(gomega.BeFalse())
+		g.Expect(p.Queue.Len()).To(gomega.Equal(0))
+	})
+
+	t.Run("resend the latest metric", func(t *testing.T) {
+		statusCode, err := p.Resend(subAccID)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(statusCode).To(gomega.Equal(http.StatusCreated))
+		g.Expect(timesVisited).To(gomega.Equal(1))
+
+		status, found := p.Statuses.Get(record.RuntimeID)
+		g.Expect(found).To(gomega.BeTrue())
+		g.Expect(status.LastEDPStatusCode).To(gomega.Equal(http.StatusCreated))
+		g.Expect(status.LastSent).ShouldNot(gomega.BeNil())
+
+		_, err = p.Resend(subAccIDWithoutMetric)
+		g.Expect(err).ShouldNot(gomega.BeNil())
+		g.Expect(timesVisited).To(gomega.Equal(1))
+
+		statusCode, err = p.ResendRuntime(record.RuntimeID)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(statusCode).To(gomega.Equal(http.StatusCreated))
+		g.Expect(timesVisited).To(gomega.Equal(2))
+	})
+}
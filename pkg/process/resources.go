@@ -0,0 +1,53 @@
+package process
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// gpuProductLabel is set by the GPU feature discovery of NVIDIA
+	gpuProductLabel = "nvidia.com/gpu.product"
+
+	nativeResourcePrefix = "kubernetes.io/"
+	quotaRequestsPrefix  = "requests."
+)
+
+// gpuResources are the extended resources of the device plugins which expose GPUs
+var gpuResources = []corev1.ResourceName{"nvidia.com/gpu", "amd.com/gpu"}
+
+// getNodeGPUs returns the number and the type of the GPUs of a node. The capacity of the node takes precedence
+// over the spec table, as the device plugin reports the GPUs which are actually attached. It also tells if the type
+// is the product reported by the node rather than the one of the spec table.
+func getNodeGPUs(node *corev1.Node, vmFeatures *Features) (int, string, bool) {
+	gpus, gpuType := vmFeatures.GPUs, vmFeatures.GPUType
+	for _, resourceName := range gpuResources {
+		if capacity, ok := node.Status.Capacity[resourceName]; ok {
+			gpus = int(capacity.Value())
+			break
+		}
+	}
+	if product := node.Labels[gpuProductLabel]; product != "" {
+		return gpus, product, true
+	}
+	return gpus, gpuType, false
+}
+
+// addExtendedResources adds the capacity of the extended resources of a node, i.e. the resources
+// which are advertised by device plugins or the cluster operator and not by Kubernetes itself
+func addExtendedResources(resources map[string]int64, node *corev1.Node) {
+	for resourceName, capacity := range node.Status.Capacity {
+		if isExtendedResource(string(resourceName)) {
+			resources[string(resourceName)] += capacity.Value()
+		}
+	}
+}
+
+// isExtendedResource checks if a resource is an extended resource, which are the domain-prefixed ones outside kubernetes.io
+func isExtendedResource(name string) bool {
+	if !strings.Contains(name, "/") || strings.Contains(name, nativeResourcePrefix) {
+		return false
+	}
+	return !strings.HasPrefix(name, quotaRequestsPrefix)
+}
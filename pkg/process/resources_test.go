@@ -0,0 +1,23 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestIsExtendedResource(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testCases := map[string]bool{
+		"nvidia.com/gpu":                true,
+		"example.com/fpga":              true,
+		"cpu":                           false,
+		"hugepages-2Mi":                 false,
+		"attachable-volumes-azure-disk": false,
+		"kubernetes.io/batch":           false,
+		"requests.nvidia.com/gpu":       false,
+	}
+	for name, expected := range testCases {
+		g.Expect(isExtendedResource(name)).To(gomega.Equal(expected), name)
+	}
+}
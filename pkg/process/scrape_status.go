@@ -0,0 +1,113 @@
+package process
+
+import (
+	"sort"
+	"time"
+
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/pkg/errors"
+)
+
+// Stages of generating and sending a metric for a runtime
+const (
+	stageCache      = "cache"
+	stageKubeconfig = "kubeconfig"
+	stageShoot      = "shoot"
+	stageSKR        = "skr"
+	stageParse      = "parse"
+	stageMarshal    = "marshal"
+	stageEDP        = "edp"
+)
+
+// scrapeError is an error which happened in a particular stage of generating a metric
+type scrapeError struct {
+	stage string
+	err   error
+}
+
+func (e scrapeError) Error() string {
+	return e.err.Error()
+}
+
+func (e scrapeError) Unwrap() error {
+	return e.err
+}
+
+// errorStage returns the stage in which the error happened
+func errorStage(err error) string {
+	var scrapeErr scrapeError
+	if errors.As(err, &scrapeErr) {
+		return scrapeErr.stage
+	}
+	return ""
+}
+
+// updateStatus updates the scrape status of a runtime. A runtime which stopped being tracked in the meantime, e.g.
+// while a worker was scraping it, gets no status again, so that the statuses do not outlive the runtimes.
+func (p Process) updateStatus(runtimeID string, updateFn func(status *metriscache.ScrapeStatus)) {
+	if !p.isTracked(runtimeID) {
+		return
+	}
+	p.Statuses.Update(runtimeID, updateFn)
+}
+
+func (p Process) recordScrapeSuccess(runtimeID string) {
+	now := time.Now()
+	p.updateStatus(runtimeID, func(status *metriscache.ScrapeStatus) {
+		status.LastSuccessfulScrape = &now
+	})
+}
+
+func (p Process) recordFailure(runtimeID, stage string, err error) {
+	now := time.Now()
+	p.updateStatus(runtimeID, func(status *metriscache.ScrapeStatus) {
+		status.LastError = err.Error()
+		status.LastErrorStage = stage
+		status.LastErrorTime = &now
+	})
+}
+
+func (p Process) recordSent(runtimeID string, statusCode int, isStale bool, err error) {
+	if err != nil {
+		p.recordFailure(runtimeID, stageEDP, err)
+	}
+	now := time.Now()
+	p.updateStatus(runtimeID, func(status *metriscache.ScrapeStatus) {
+		if statusCode != 0 {
+			status.LastEDPStatusCode = statusCode
+		}
+		if err == nil {
+			status.LastSent = &now
+			status.LastPayloadStale = isStale
+		}
+	})
+}
+
+func (p Process) recordNextScrape(runtimeID string, nextScrape time.Time) {
+	p.updateStatus(runtimeID, func(status *metriscache.ScrapeStatus) {
+		status.NextScrape = &nextScrape
+	})
+}
+
+// ScrapeStatuses returns the scrape status of all the tracked runtimes sorted by subAccountID and runtimeID
+func (p Process) ScrapeStatuses() []metriscache.ScrapeStatus {
+	statuses := make([]metriscache.ScrapeStatus, 0, p.Cache.ItemCount())
+	for runtimeID, item := range p.Cache.Items() {
+		record, ok := item.Object.(metriscache.Record)
+		if !ok {
+			continue
+		}
+		status, _ := p.Statuses.Get(runtimeID)
+		status.SubAccountID = record.SubAccountID
+		status.RuntimeID = runtimeID
+		status.ShootName = record.ShootName
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].SubAccountID != statuses[j].SubAccountID {
+			return statuses[i].SubAccountID < statuses[j].SubAccountID
+		}
+		return statuses[i].RuntimeID < statuses[j].RuntimeID
+	})
+	return statuses
+}
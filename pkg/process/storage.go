@@ -0,0 +1,147 @@
+package process
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kyma-incubator/metris/pkg/edp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	storageProvisionerAnnotation     = "volume.kubernetes.io/storage-provisioner"
+	betaStorageProvisionerAnnotation = "volume.beta.kubernetes.io/storage-provisioner"
+	provisionedByAnnotation          = "pv.kubernetes.io/provisioned-by"
+)
+
+// diskTypeAttributes are the attributes of the CSI volumes which hold the type of the disk, by driver
+var diskTypeAttributes = []string{"skuName", "skuname", "type"}
+
+// localProvisioners provision the volumes on the disks of the nodes, which are accounted with the nodes already
+var localProvisioners = map[string]bool{
+	"kubernetes.io/no-provisioner": true,
+	"kubernetes.io/host-path":      true,
+	"rancher.io/local-path":        true,
+	"openebs.io/local":             true,
+}
+
+// getVolumeStorage returns the storage of the cloud-backed volumes per storage class. The storage is taken from the PVs,
+// which includes the statically provisioned ones and the released ones which are retained, as their disks are still there.
+// The bound PVCs whose PV is not listed are accounted by their capacity.
+func getVolumeStorage(pvcList *corev1.PersistentVolumeClaimList, pvList *corev1.PersistentVolumeList) []edp.StorageClass {
+	storageClasses := make(map[string]*edp.StorageClass)
+	add := func(className, provisioner, diskType string, size *resource.Quantity) *edp.StorageClass {
+		key := className + "/" + provisioner + "/" + diskType
+		if _, ok := storageClasses[key]; !ok {
+			storageClasses[key] = &edp.StorageClass{Name: className, Provisioner: provisioner, DiskType: diskType}
+		}
+		storageClasses[key].Count += 1
+		storageClasses[key].SizeGb += getSizeInGiB(size)
+		return storageClasses[key]
+	}
+
+	pvs := make(map[string]bool)
+	if pvList != nil {
+		for i := range pvList.Items {
+			pv := &pvList.Items[i]
+			pvs[pv.Name] = true
+			provisioner := getVolumeProvisioner(pv)
+			if isLocalVolume(provisioner, pv) {
+				continue
+			}
+			switch {
+			case pv.Status.Phase == corev1.VolumeBound || pv.Status.Phase == corev1.VolumeAvailable:
+				add(pv.Spec.StorageClassName, provisioner, getDiskType(pv), pv.Spec.Capacity.Storage())
+			case pv.Status.Phase == corev1.VolumeReleased && pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain:
+				add(pv.Spec.StorageClassName, provisioner, getDiskType(pv), pv.Spec.Capacity.Storage()).Released += 1
+			}
+		}
+	}
+
+	if pvcList != nil {
+		for _, pvc := range pvcList.Items {
+			if pvc.Status.Phase != corev1.ClaimBound || pvs[pvc.Spec.VolumeName] {
+				continue
+			}
+			provisioner := getClaimProvisioner(&pvc)
+			if isLocalVolume(provisioner, nil) {
+				continue
+			}
+			className := ""
+			if pvc.Spec.StorageClassName != nil {
+				className = *pvc.Spec.StorageClassName
+			}
+			add(className, provisioner, "", pvc.Status.Capacity.Storage())
+		}
+	}
+
+	var sorted []edp.StorageClass
+	for _, storageClass := range storageClasses {
+		sorted = append(sorted, *storageClass)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		if sorted[i].Provisioner != sorted[j].Provisioner {
+			return sorted[i].Provisioner < sorted[j].Provisioner
+		}
+		return sorted[i].DiskType < sorted[j].DiskType
+	})
+	return sorted
+}
+
+// getClaimProvisioner returns the provisioner of the volume of a PVC
+func getClaimProvisioner(pvc *corev1.PersistentVolumeClaim) string {
+	if provisioner, ok := pvc.Annotations[storageProvisionerAnnotation]; ok {
+		return provisioner
+	}
+	return pvc.Annotations[betaStorageProvisionerAnnotation]
+}
+
+// getVolumeProvisioner returns the provisioner of a PV. A statically provisioned CSI volume falls back to its driver.
+func getVolumeProvisioner(pv *corev1.PersistentVolume) string {
+	if provisioner, ok := pv.Annotations[provisionedByAnnotation]; ok {
+		return provisioner
+	}
+	if pv.Spec.CSI != nil {
+		return pv.Spec.CSI.Driver
+	}
+	return ""
+}
+
+// getDiskType returns the type of the disk of the cloud provider which backs a PV, empty if it is unknown
+func getDiskType(pv *corev1.PersistentVolume) string {
+	if pv.Spec.CSI != nil {
+		for _, attribute := range diskTypeAttributes {
+			if diskType, ok := pv.Spec.CSI.VolumeAttributes[attribute]; ok {
+				return diskType
+			}
+		}
+	}
+	return ""
+}
+
+// isLocalVolume checks if a volume is on the disk of a node rather than a disk of the cloud provider
+func isLocalVolume(provisioner string, pv *corev1.PersistentVolume) bool {
+	if localProvisioners[provisioner] {
+		return true
+	}
+	return pv != nil && (pv.Spec.Local != nil || pv.Spec.HostPath != nil)
+}
+
+// getTotalStorage returns the size in GB rounded up and the number of the volumes of all storage classes
+func getTotalStorage(storageClasses []edp.StorageClass) (int64, int) {
+	size, count := 0.0, 0
+	for _, storageClass := range storageClasses {
+		size += storageClass.SizeGb
+		count += storageClass.Count
+	}
+	return int64(math.Ceil(size)), count
+}
+
+// getSizeInGiB converts any value to GiB without truncating it
+func getSizeInGiB(value *resource.Quantity) float64 {
+	return float64(value.Value()) / math.Pow(2, 30)
+}
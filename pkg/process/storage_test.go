@@ -0,0 +1,71 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/kyma-incubator/metris/pkg/edp"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetVolumeStorage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	newPVC := func(name, capacity, storageClass, provisioner, volumeName string) corev1.PersistentVolumeClaim {
+		pvc := metristesting.GetPV(name, "foo", capacity)
+		if storageClass != "" {
+			pvc.Spec.StorageClassName = &storageClass
+		}
+		if provisioner != "" {
+			pvc.Annotations = map[string]string{storageProvisionerAnnotation: provisioner}
+		}
+		pvc.Spec.VolumeName = volumeName
+		return *pvc
+	}
+	pending := newPVC("pending", "1Gi", "default", "disk.csi.azure.com", "")
+	pending.Status.Phase = corev1.ClaimPending
+	pvcList := &corev1.PersistentVolumeClaimList{Items: []corev1.PersistentVolumeClaim{
+		// The PVCs whose PV is not listed are accounted by their capacity
+		newPVC("fraction", "1536Mi", "default", "disk.csi.azure.com", ""),
+		newPVC("small", "500Mi", "default", "disk.csi.azure.com", ""),
+		newPVC("local-path", "20Gi", "local-path", "rancher.io/local-path", ""),
+		// The PVCs whose PV is listed are accounted by their PV
+		newPVC("bound", "8Gi", "default", "disk.csi.azure.com", "bound"),
+		newPVC("local", "20Gi", "", "", "local"),
+		pending,
+	}}
+
+	newPV := func(name, capacity string, phase corev1.PersistentVolumePhase, reclaimPolicy corev1.PersistentVolumeReclaimPolicy) *corev1.PersistentVolume {
+		return metristesting.GetPersistentVolume(name, capacity, phase, reclaimPolicy)
+	}
+	// The disk of a PV can be larger than requested
+	bound := newPV("bound", "10Gi", corev1.VolumeBound, corev1.PersistentVolumeReclaimDelete)
+	local := newPV("local", "20Gi", corev1.VolumeBound, corev1.PersistentVolumeReclaimRetain)
+	local.Spec.CSI = nil
+	local.Spec.Local = &corev1.LocalVolumeSource{Path: "/mnt/disks/ssd1"}
+	static := newPV("static", "100Gi", corev1.VolumeAvailable, corev1.PersistentVolumeReclaimRetain)
+	static.Annotations = nil
+	static.Spec.StorageClassName = ""
+	static.Spec.CSI.VolumeAttributes = map[string]string{"skuname": "Premium_LRS"}
+	pvList := &corev1.PersistentVolumeList{Items: []corev1.PersistentVolume{
+		*bound,
+		*local,
+		*static,
+		*newPV("retained", "5Gi", corev1.VolumeReleased, corev1.PersistentVolumeReclaimRetain),
+		*newPV("deleted", "5Gi", corev1.VolumeReleased, corev1.PersistentVolumeReclaimDelete),
+	}}
+
+	storageClasses := getVolumeStorage(pvcList, pvList)
+	g.Expect(storageClasses).To(gomega.Equal([]edp.StorageClass{
+		{Provisioner: "disk.csi.azure.com", DiskType: "Premium_LRS", Count: 1, SizeGb: 100},
+		// The size is not truncated to whole GB
+		{Name: "default", Provisioner: "disk.csi.azure.com", Count: 2, SizeGb: 1.5 + 500.0/1024},
+		{Name: "default", Provisioner: "disk.csi.azure.com", DiskType: "StandardSSD_LRS", Count: 2, SizeGb: 15, Released: 1},
+	}))
+
+	size, count := getTotalStorage(storageClasses)
+	g.Expect(size).To(gomega.Equal(int64(117)))
+	g.Expect(count).To(gomega.Equal(5))
+
+	g.Expect(getVolumeStorage(nil, nil)).To(gomega.BeEmpty())
+}
@@ -0,0 +1,74 @@
+package process
+
+import (
+	"time"
+
+	gardenerv1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const operationStateFailed = "failed"
+
+// isSuspended checks if the latest suspension of the runtime in KEB was not followed by an unsuspension
+func isSuspended(runtime *kebruntime.RuntimeDTO) bool {
+	suspension := latestOperation(runtime.Status.Suspension)
+	if suspension == nil {
+		return false
+	}
+	unsuspension := latestOperation(runtime.Status.Unsuspension)
+	return unsuspension == nil || suspension.CreatedAt.After(unsuspension.CreatedAt)
+}
+
+// latestOperation returns the latest operation which did not fail
+func latestOperation(operations kebruntime.OperationsData) *kebruntime.Operation {
+	var latest *kebruntime.Operation
+	for i := range operations.Data {
+		operation := &operations.Data[i]
+		if operation.State == operationStateFailed {
+			continue
+		}
+		if latest == nil || operation.CreatedAt.After(latest.CreatedAt) {
+			latest = operation
+		}
+	}
+	return latest
+}
+
+// isHibernated checks if the shoot is hibernated or is being hibernated. Its API server cannot be reached then.
+func isHibernated(shoot *gardenerv1beta1.Shoot) bool {
+	if shoot.Status.IsHibernated {
+		return true
+	}
+	hibernation := shoot.Spec.Hibernation
+	return hibernation != nil && hibernation.Enabled != nil && *hibernation.Enabled
+}
+
+// suspendedMetric returns a metric without any compute for a suspended or hibernated runtime.
+// Only the storage which was retained from the last scrape is reported.
+func suspendedMetric(record metriscache.Record, interval time.Duration) *edp.ConsumptionMetrics {
+	timestamp := getTimestampNow(interval)
+	metric := &edp.ConsumptionMetrics{
+		Timestamp: timestamp,
+		Compute: edp.Compute{
+			VMTypes:            []edp.VMType{},
+			ProvisionedVolumes: record.RetainedVolumes,
+		},
+		Suspended: true,
+	}
+	setRuntimeMetadata(metric, record)
+	metric.EventID = edp.NewConsumptionEventID(record.SubAccountID, edp.VariantSuspended, *metric)
+	return metric
+}
+
+// getRetainedVolumes returns the volumes which outlive the nodes, i.e. the bound PVCs and the retained PVs
+func getRetainedVolumes(pvcList *corev1.PersistentVolumeClaimList, pvList *corev1.PersistentVolumeList) edp.ProvisionedVolumes {
+	size, count := getTotalStorage(getVolumeStorage(pvcList, pvList))
+	return edp.ProvisionedVolumes{
+		SizeGbTotal:   size,
+		Count:         count,
+		SizeGbRounded: getVolumeRoundedToFactor(size),
+	}
+}
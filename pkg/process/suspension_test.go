@@ -0,0 +1,167 @@
+package process
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	gardenerv1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/google/uuid"
+	metriscache "github.com/kyma-incubator/metris/pkg/cache"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	kebruntime "github.com/kyma-project/control-plane/components/kyma-environment-broker/common/runtime"
+	"github.com/onsi/gomega"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestIsSuspended(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	now := time.Now()
+	operations := func(ops ...kebruntime.Operation) kebruntime.OperationsData {
+		return kebruntime.OperationsData{Data: ops, Count: len(ops), TotalCount: len(ops)}
+	}
+
+	testCases := []struct {
+		name         string
+		suspension   kebruntime.OperationsData
+		unsuspension kebruntime.OperationsData
+		expected     bool
+	}{
+		{
+			name:     "never suspended",
+			expected: false,
+		},
+		{
+			name:       "suspended",
+			suspension: operations(kebruntime.Operation{State: "succeeded", CreatedAt: now}),
+			expected:   true,
+		},
+		{
+			name:         "unsuspended after the suspension",
+			suspension:   operations(kebruntime.Operation{State: "succeeded", CreatedAt: now.Add(-time.Hour)}),
+			unsuspension: operations(kebruntime.Operation{State: "in progress", CreatedAt: now}),
+			expected:     false,
+		},
+		{
+			name: "suspended again after the unsuspension",
+			suspension: operations(
+				kebruntime.Operation{State: "succeeded", CreatedAt: now.Add(-2 * time.Hour)},
+				kebruntime.Operation{State: "succeeded", CreatedAt: now},
+			),
+			unsuspension: operations(kebruntime.Operation{State: "succeeded", CreatedAt: now.Add(-time.Hour)}),
+			expected:     true,
+		},
+		{
+			name:       "failed suspension",
+			suspension: operations(kebruntime.Operation{State: "failed", CreatedAt: now}),
+			expected:   false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runtime := metristesting.NewRuntimesDTO(uuid.New().String(), "shoot")
+			runtime.Status.Suspension = tc.suspension
+			runtime.Status.Unsuspension = tc.unsuspension
+			g.Expect(isSuspended(&runtime)).To(gomega.Equal(tc.expected))
+		})
+	}
+}
+
+func TestGenerateRecordWhileSuspended(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	retainedVolumes := edp.ProvisionedVolumes{SizeGbTotal: 20, Count: 2, SizeGbRounded: 32}
+
+	t.Run("suspended runtime is not scraped", func(t *testing.T) {
+		record := NewRecord(uuid.New().String(), "shoot")
+		record.Suspended = true
+		record.RetainedVolumes = retainedVolumes
+		cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
+		g.Expect(cache.Add(record.RuntimeID, record, gocache.NoExpiration)).Should(gomega.BeNil())
+
+		// No clients are set as the cluster must not be reached
+		p := Process{
+			Queue:  workqueue.NewDelayingQueue(),
+			Cache:  cache,
+			Logger: logrus.New(),
+		}
+		gotRecord, err := p.generateRecordWithMetrics(1, record.RuntimeID)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(gotRecord.Metric.Suspended).To(gomega.BeTrue())
+		g.Expect(gotRecord.Metric.RuntimeID).To(gomega.Equal(record.RuntimeID))
+		g.Expect(gotRecord.Metric.Compute.ProvisionedCpus).To(gomega.Equal(0))
+		g.Expect(gotRecord.Metric.Compute.VMTypes).To(gomega.BeEmpty())
+		g.Expect(gotRecord.Metric.Compute.ProvisionedVolumes).To(gomega.Equal(retainedVolumes))
+	})
+
+	t.Run("hibernated shoot is not scraped", func(t *testing.T) {
+		shootName := "hibernated-shoot"
+		record := NewRecord(uuid.New().String(), shootName)
+		record.RetainedVolumes = retainedVolumes
+		cache := gocache.New(gocache.NoExpiration, gocache.NoExpiration)
+		g.Expect(cache.Add(record.RuntimeID, record, gocache.NoExpiration)).Should(gomega.BeNil())
+
+		hibernated := true
+		shootClient, err := NewFakeShootClient(metristesting.GetShoot(shootName, func(shoot *gardenerv1beta1.Shoot) {
+			shoot.Spec.Hibernation = &gardenerv1beta1.Hibernation{Enabled: &hibernated}
+		}))
+		g.Expect(err).Should(gomega.BeNil())
+		p := Process{
+			Queue:       workqueue.NewDelayingQueue(),
+			Cache:       cache,
+			ShootClient: shootClient,
+			Logger:      logrus.New(),
+		}
+		gotRecord, err := p.generateRecordWithMetrics(1, record.RuntimeID)
+		g.Expect(err).Should(gomega.BeNil())
+		g.Expect(gotRecord.Metric.Suspended).To(gomega.BeTrue())
+		g.Expect(gotRecord.Metric.Compute.ProvisionedVolumes).To(gomega.Equal(retainedVolumes))
+	})
+}
+
+func TestPopulateCacheAndQueueOnSuspension(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	subAccID := uuid.New().String()
+
+	// The unsuspension is sent as a lifecycle event
+	expectedPath := fmt.Sprintf("/namespaces/%s/dataStreams/%s/%s/dataTenants/%s/%s/events", testNamespace, testLifecycleStream, edp.LifecycleSchemaVersion, subAccID, testEnv)
+	srv := metristesting.StartTestServer(expectedPath, func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusCreated)
+	}, g)
+	defer srv.Close()
+	edpClient, err := edp.NewClient(newEDPConfig(srv.URL), logrus.New())
+	g.Expect(err).Should(gomega.BeNil())
+
+	p := Process{
+		EDPClient: edpClient,
+		Queue:     workqueue.NewDelayingQueue(),
+		Cache:     gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+		Logger:    logrus.New(),
+	}
+	record := NewRecord(subAccID, "shoot")
+	record.Metric = NewMetric()
+	g.Expect(p.Cache.Add(record.RuntimeID, record, gocache.NoExpiration)).Should(gomega.BeNil())
+
+	getRecord := func() metriscache.Record {
+		obj, found := p.Cache.Get(record.RuntimeID)
+		g.Expect(found).To(gomega.BeTrue())
+		return obj.(metriscache.Record)
+	}
+
+	// The last metric is kept while the runtime is suspended
+	runtime := metristesting.NewRuntimesDTO(subAccID, "shoot")
+	runtime.Status.Suspension.Data = []kebruntime.Operation{{State: "succeeded", CreatedAt: time.Now().Add(-time.Hour)}}
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{runtime}})
+	g.Expect(getRecord().Suspended).To(gomega.BeTrue())
+	g.Expect(getRecord().Metric).To(gomega.Equal(NewMetric()))
+	g.Expect(p.Queue.Len()).To(gomega.Equal(0))
+
+	// The runtime is scraped right away once it is unsuspended
+	runtime.Status.Unsuspension.Data = []kebruntime.Operation{{State: "succeeded", CreatedAt: time.Now()}}
+	p.populateCacheAndQueue(&kebruntime.RuntimesPage{Data: []kebruntime.RuntimeDTO{runtime}})
+	g.Expect(getRecord().Suspended).To(gomega.BeFalse())
+	g.Expect(p.Queue.Len()).To(gomega.Equal(1))
+}
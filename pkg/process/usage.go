@@ -0,0 +1,27 @@
+package process
+
+import (
+	"encoding/json"
+
+	"github.com/kyma-incubator/metris/pkg/aggregation"
+	"github.com/kyma-incubator/metris/pkg/edp"
+)
+
+// sendUsageWindow sends the usage of a tenant in a closed window to the usage datastream
+func (p Process) sendUsageWindow(summary aggregation.Summary) {
+	summary.Usage.SchemaVersion = edp.UsageSchemaVersion
+	if err := edp.Validate(summary.Usage); err != nil {
+		p.Logger.Errorf("dropped invalid usage window for subAccountID: %s: %v", summary.Tenant, err)
+		return
+	}
+	payload, err := json.Marshal(summary.Usage)
+	if err != nil {
+		p.Logger.Errorf("failed to json.Marshal usage window for subAccountID: %s: %v", summary.Tenant, err)
+		return
+	}
+	if err := p.deliver(p.EDPClient.Config.UsageDataStreamName, summary.Tenant, payload); err != nil {
+		p.Logger.Errorf("failed to send usage window starting at %s for subAccountID: %s: %v", summary.Usage.WindowStart, summary.Tenant, err)
+		return
+	}
+	p.Logger.Infof("successfully sent usage window starting at %s for subAccountID: %s", summary.Usage.WindowStart, summary.Tenant)
+}
@@ -0,0 +1,126 @@
+package process
+
+import (
+	"sort"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	workerPoolLabel = "worker.gardener.cloud/pool"
+	// defaultVolumeType is the type of the volumes of the workers which leave it to the provider
+	defaultVolumeType = "default"
+)
+
+// getWorkerPools returns the compute of every worker pool of the shoot. The nodes are matched to their pool by label.
+// A pool which was removed from the shoot is reported as long as it has nodes left.
+func getWorkerPools(shoot *gardencorev1beta1.Shoot, nodeList *corev1.NodeList) []edp.WorkerPool {
+	nodes := make(map[string]int)
+	machineTypes := make(map[string]string)
+	for _, node := range nodeList.Items {
+		pool, ok := node.Labels[workerPoolLabel]
+		if !ok {
+			continue
+		}
+		nodes[pool] += 1
+		machineTypes[pool] = node.Labels[nodeInstanceTypeLabel]
+	}
+
+	var pools []edp.WorkerPool
+	for _, worker := range shoot.Spec.Provider.Workers {
+		pool := edp.WorkerPool{
+			Name:        worker.Name,
+			MachineType: worker.Machine.Type,
+			Nodes:       nodes[worker.Name],
+			Minimum:     int(worker.Minimum),
+			Maximum:     int(worker.Maximum),
+			Zones:       worker.Zones,
+			// Gardener schedules the system components on every pool unless it is disallowed
+			System: worker.SystemComponents == nil || worker.SystemComponents.Allow,
+		}
+		if worker.Volume != nil {
+			if worker.Volume.Type != nil {
+				pool.VolumeType = *worker.Volume.Type
+			}
+			pool.VolumeSizeGb = getWorkerVolumeSizeInGB(worker.Volume)
+		}
+		pools = append(pools, pool)
+		delete(nodes, worker.Name)
+	}
+
+	var removedPools []string
+	for name := range nodes {
+		removedPools = append(removedPools, name)
+	}
+	sort.Strings(removedPools)
+	for _, name := range removedPools {
+		pools = append(pools, edp.WorkerPool{
+			Name:        name,
+			MachineType: machineTypes[name],
+			Nodes:       nodes[name],
+		})
+	}
+	return pools
+}
+
+// getWorkers returns the workers of the shoot by the name of their pool
+func getWorkers(shoot *gardencorev1beta1.Shoot) map[string]*gardencorev1beta1.Worker {
+	workers := make(map[string]*gardencorev1beta1.Worker)
+	for i := range shoot.Spec.Provider.Workers {
+		worker := &shoot.Spec.Provider.Workers[i]
+		workers[worker.Name] = worker
+	}
+	return workers
+}
+
+// getNodeVolume returns the type and the size in GB of the root volume of a node of the worker. The size falls back
+// to the storage of the machine type in the spec table if the worker does not configure its volume.
+func getNodeVolume(worker *gardencorev1beta1.Worker, specStorage int64) (string, int64) {
+	volumeType, size := defaultVolumeType, specStorage
+	if worker == nil || worker.Volume == nil {
+		return volumeType, size
+	}
+	if worker.Volume.Type != nil && *worker.Volume.Type != "" {
+		volumeType = *worker.Volume.Type
+	}
+	if volumeSize := getWorkerVolumeSizeInGB(worker.Volume); volumeSize > 0 {
+		size = volumeSize
+	}
+	return volumeType, size
+}
+
+// sortVMTypes returns the VM types sorted by name, so that the same nodes always yield the same payload
+func sortVMTypes(vmTypes map[string]*edp.VMType) []edp.VMType {
+	var sorted []edp.VMType
+	for _, vmType := range vmTypes {
+		sorted = append(sorted, *vmType)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// sortVolumeTypes returns the volume types sorted by type
+func sortVolumeTypes(volumeTypes map[string]*edp.VolumeType) []edp.VolumeType {
+	var sorted []edp.VolumeType
+	for _, volumeType := range volumeTypes {
+		sorted = append(sorted, *volumeType)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Type < sorted[j].Type
+	})
+	return sorted
+}
+
+// getWorkerVolumeSizeInGB returns the size of the volume of a worker in GB, 0 if it cannot be parsed
+func getWorkerVolumeSizeInGB(volume *gardencorev1beta1.Volume) int64 {
+	size, err := resource.ParseQuantity(volume.VolumeSize)
+	if err != nil {
+		return 0
+	}
+	return getSizeInGB(&size)
+}
@@ -0,0 +1,76 @@
+package process
+
+import (
+	"testing"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/kyma-incubator/metris/pkg/edp"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetWorkerPools(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	volumeType := "Premium_LRS"
+	shoot := metristesting.GetShoot("testShoot", func(shoot *gardencorev1beta1.Shoot) {
+		shoot.Spec.Provider.Workers = []gardencorev1beta1.Worker{
+			{
+				Name:    "system",
+				Machine: gardencorev1beta1.Machine{Type: "Standard_D8_v3"},
+				Minimum: 1,
+				Maximum: 3,
+			},
+			{
+				Name:             "customer",
+				Machine:          gardencorev1beta1.Machine{Type: "Standard_D4_v3"},
+				Minimum:          0,
+				Maximum:          10,
+				Zones:            []string{"1", "2", "3"},
+				Volume:           &gardencorev1beta1.Volume{Type: &volumeType, VolumeSize: "128Gi"},
+				SystemComponents: &gardencorev1beta1.WorkerSystemComponents{Allow: false},
+			},
+		}
+	})
+	newNode := func(name, vmType, pool string) corev1.Node {
+		node := metristesting.GetNode(name, vmType)
+		node.Labels[workerPoolLabel] = pool
+		return node
+	}
+	nodeList := &corev1.NodeList{Items: []corev1.Node{
+		newNode("node1", "Standard_D8_v3", "system"),
+		newNode("node2", "Standard_D4_v3", "customer"),
+		newNode("node3", "Standard_D4_v3", "customer"),
+		// The nodes of a removed pool are drained
+		newNode("node4", "Standard_D2_v3", "removed"),
+	}}
+
+	g.Expect(getWorkerPools(shoot, nodeList)).To(gomega.Equal([]edp.WorkerPool{
+		{Name: "system", MachineType: "Standard_D8_v3", Nodes: 1, Minimum: 1, Maximum: 3, System: true},
+		{
+			Name:         "customer",
+			MachineType:  "Standard_D4_v3",
+			Nodes:        2,
+			Maximum:      10,
+			Zones:        []string{"1", "2", "3"},
+			VolumeType:   "Premium_LRS",
+			VolumeSizeGb: 128,
+		},
+		{Name: "removed", MachineType: "Standard_D2_v3", Nodes: 1},
+	}))
+}
+
+func TestSortVMTypes(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	vmTypes := map[string]*edp.VMType{
+		"standard_nc6s_v3": {Name: "standard_nc6s_v3", Count: 1},
+		"standard_d8_v3":   {Name: "standard_d8_v3", Count: 3},
+		"standard_d4_v3":   {Name: "standard_d4_v3", Count: 2},
+	}
+	g.Expect(sortVMTypes(vmTypes)).To(gomega.Equal([]edp.VMType{
+		{Name: "standard_d4_v3", Count: 2},
+		{Name: "standard_d8_v3", Count: 3},
+		{Name: "standard_nc6s_v3", Count: 1},
+	}))
+	g.Expect(sortVMTypes(nil)).To(gomega.BeNil())
+}
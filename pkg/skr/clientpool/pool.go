@@ -0,0 +1,146 @@
+package clientpool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Pool shares one dynamic client, and with it one REST config and HTTP transport, per SKR kubeconfig
+// for all the resource types which are scraped. Clients which are not used for IdleTimeout are evicted.
+type Pool struct {
+	IdleTimeout time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+type pooledClient struct {
+	client    dynamic.Interface
+	transport http.RoundTripper
+	lastUsed  time.Time
+}
+
+func NewPool(idleTimeout time.Duration) *Pool {
+	return &Pool{
+		IdleTimeout: idleTimeout,
+		clients:     make(map[string]*pooledClient),
+	}
+}
+
+// Get returns the dynamic client for a kubeconfig and creates it if it does not exist in the pool yet.
+// A nil pool creates a new client on every call.
+func (p *Pool) Get(kubeconfig string) (dynamic.Interface, error) {
+	if p == nil {
+		client, _, err := newClient(kubeconfig)
+		return client, err
+	}
+
+	key := hashKubeconfig(kubeconfig)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, found := p.clients[key]; found {
+		pooled.lastUsed = time.Now()
+		return pooled.client, nil
+	}
+
+	client, transport, err := newClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[key] = &pooledClient{
+		client:    client,
+		transport: transport,
+		lastUsed:  time.Now(),
+	}
+	return client, nil
+}
+
+// Len returns the number of clients in the pool
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
+
+// EvictIdle removes the clients which were not used for IdleTimeout and closes their idle connections
+func (p *Pool) EvictIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, pooled := range p.clients {
+		if time.Since(pooled.lastUsed) < p.IdleTimeout {
+			continue
+		}
+		closeIdleConnections(pooled.transport)
+		delete(p.clients, key)
+	}
+}
+
+// Run evicts the idle clients periodically until the context is done. Nothing is evicted without an idle timeout.
+func (p *Pool) Run(ctx context.Context) {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+	interval := p.IdleTimeout / 2
+	if interval <= 0 {
+		interval = p.IdleTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.EvictIdle()
+		}
+	}
+}
+
+func newClient(kubeconfig string) (dynamic.Interface, http.RoundTripper, error) {
+	restClientConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
+	if err != nil {
+		return nil, nil, err
+	}
+	transport, err := rest.TransportFor(restClientConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The transport already carries the TLS and auth settings hence they are dropped from the config
+	sharedConfig := rest.AnonymousClientConfig(restClientConfig)
+	sharedConfig.TLSClientConfig = rest.TLSClientConfig{}
+	sharedConfig.Transport = transport
+	client, err := dynamic.NewForConfig(sharedConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, transport, nil
+}
+
+func closeIdleConnections(transport http.RoundTripper) {
+	for transport != nil {
+		if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+			return
+		}
+		wrapper, ok := transport.(utilnet.RoundTripperWrapper)
+		if !ok {
+			return
+		}
+		transport = wrapper.WrappedRoundTripper()
+	}
+}
+
+func hashKubeconfig(kubeconfig string) string {
+	sum := sha256.Sum256([]byte(kubeconfig))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package clientpool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+const kubeconfigFormat = `apiVersion: v1
+kind: Config
+clusters:
+- name: shoot
+  cluster:
+    server: https://%s.example.com
+contexts:
+- name: shoot
+  context:
+    cluster: shoot
+    user: shoot
+current-context: shoot
+users:
+- name: shoot
+  user:
+    token: %s
+`
+
+func TestGet(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pool := NewPool(time.Minute)
+
+	fooClient, err := pool.Get(newKubeconfig("foo", "token"))
+	g.Expect(err).Should(gomega.BeNil())
+	gotFooClient, err := pool.Get(newKubeconfig("foo", "token"))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotFooClient).To(gomega.BeIdenticalTo(fooClient))
+	g.Expect(pool.Len()).To(gomega.Equal(1))
+
+	// A rotated kubeconfig gets its own client
+	rotatedFooClient, err := pool.Get(newKubeconfig("foo", "rotated-token"))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(rotatedFooClient).ToNot(gomega.BeIdenticalTo(fooClient))
+	g.Expect(pool.Len()).To(gomega.Equal(2))
+
+	_, err = pool.Get("not a kubeconfig")
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	g.Expect(pool.Len()).To(gomega.Equal(2))
+
+	var nilPool *Pool
+	_, err = nilPool.Get(newKubeconfig("foo", "token"))
+	g.Expect(err).Should(gomega.BeNil())
+}
+
+func TestEvictIdle(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pool := NewPool(time.Minute)
+
+	_, err := pool.Get(newKubeconfig("foo", "token"))
+	g.Expect(err).Should(gomega.BeNil())
+	_, err = pool.Get(newKubeconfig("bar", "token"))
+	g.Expect(err).Should(gomega.BeNil())
+
+	pool.EvictIdle()
+	g.Expect(pool.Len()).To(gomega.Equal(2))
+
+	// Mark foo as idle
+	pool.clients[hashKubeconfig(newKubeconfig("foo", "token"))].lastUsed = time.Now().Add(-2 * time.Minute)
+	pool.EvictIdle()
+	g.Expect(pool.Len()).To(gomega.Equal(1))
+	_, found := pool.clients[hashKubeconfig(newKubeconfig("bar", "token"))]
+	g.Expect(found).To(gomega.BeTrue())
+}
+
+func newKubeconfig(shootName, token string) string {
+	return fmt.Sprintf(kubeconfigFormat, shootName, token)
+}
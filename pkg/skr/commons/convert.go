@@ -0,0 +1,17 @@
+package commons
+
+import (
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// FromUnstructured converts an unstructured resource of a SKR into its typed object
+func FromUnstructured(unstructuredObj *unstructured.Unstructured, obj metaV1.Object) error {
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(unstructuredObj.Object, obj); err != nil {
+		return err
+	}
+	// Managed fields are not needed for metering and only waste memory
+	obj.SetManagedFields(nil)
+	return nil
+}
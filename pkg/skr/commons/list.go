@@ -0,0 +1,63 @@
+package commons
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// ListPageSize is the max number of items fetched with a single list call from a SKR
+	ListPageSize = 500
+	// maxListRestarts is the max number of times a list is restarted after its continue token expired
+	maxListRestarts = 3
+)
+
+// ListInChunks lists the resources in pages of ListPageSize and calls itemFn for each of the items,
+// so that the whole list never has to be held in memory in its unstructured form.
+// The list is restarted if the continue token expired in between, skipping the items which were already processed.
+func ListInChunks(ctx context.Context, resource dynamic.ResourceInterface, opts metaV1.ListOptions, itemFn func(*unstructured.Unstructured) error) error {
+	opts.Limit = ListPageSize
+	processed := make(map[types.NamespacedName]bool)
+	restarts := 0
+	for {
+		unstructuredList, err := resource.List(ctx, opts)
+		if err != nil {
+			if opts.Continue == "" {
+				return err
+			}
+			if isExpired(err) && restarts < maxListRestarts {
+				restarts++
+				opts.Continue = ""
+				continue
+			}
+			return errors.Wrapf(err, "failed to list next page")
+		}
+		for i := range unstructuredList.Items {
+			item := &unstructuredList.Items[i]
+			key := types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()}
+			if processed[key] {
+				continue
+			}
+			processed[key] = true
+			if err := itemFn(item); err != nil {
+				return err
+			}
+		}
+
+		opts.Continue = unstructuredList.GetContinue()
+		if opts.Continue == "" {
+			return nil
+		}
+	}
+}
+
+// isExpired checks if a list failed with 410 Gone as its continue token expired
+func isExpired(err error) bool {
+	return k8serrors.IsResourceExpired(err) || k8serrors.IsGone(err)
+}
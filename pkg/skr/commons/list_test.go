@@ -0,0 +1,81 @@
+package commons
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/onsi/gomega"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// pagedResource serves one item per page
+type pagedResource struct {
+	dynamic.ResourceInterface
+	pages          int
+	gotListOptions []metaV1.ListOptions
+	// expireAt is the continue token which expires once
+	expireAt string
+}
+
+func (r *pagedResource) List(_ context.Context, opts metaV1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.gotListOptions = append(r.gotListOptions, opts)
+	if r.expireAt != "" && opts.Continue == r.expireAt {
+		r.expireAt = ""
+		return nil, k8serrors.NewResourceExpired("continue token expired")
+	}
+	page := 1
+	if opts.Continue != "" {
+		page, _ = strconv.Atoi(opts.Continue)
+	}
+	item := unstructured.Unstructured{}
+	item.SetName(fmt.Sprintf("node%d", page))
+	list := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{item}}
+	if page < r.pages {
+		list.SetContinue(strconv.Itoa(page + 1))
+	}
+	return list, nil
+}
+
+func TestListInChunks(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	resource := &pagedResource{pages: 3}
+	var gotNames []string
+	err := ListInChunks(ctx, resource, metaV1.ListOptions{FieldSelector: "foo=bar"}, func(item *unstructured.Unstructured) error {
+		gotNames = append(gotNames, item.GetName())
+		return nil
+	})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotNames).To(gomega.Equal([]string{"node1", "node2", "node3"}))
+	g.Expect(resource.gotListOptions).To(gomega.Equal([]metaV1.ListOptions{
+		{FieldSelector: "foo=bar", Limit: ListPageSize},
+		{FieldSelector: "foo=bar", Limit: ListPageSize, Continue: "2"},
+		{FieldSelector: "foo=bar", Limit: ListPageSize, Continue: "3"},
+	}))
+
+	// Errors of the item func stop the listing
+	resource = &pagedResource{pages: 3}
+	err = ListInChunks(ctx, resource, metaV1.ListOptions{}, func(item *unstructured.Unstructured) error {
+		return fmt.Errorf("failed to process %s", item.GetName())
+	})
+	g.Expect(err).ShouldNot(gomega.BeNil())
+	g.Expect(err.Error()).To(gomega.Equal("failed to process node1"))
+	g.Expect(len(resource.gotListOptions)).To(gomega.Equal(1))
+
+	// An expired continue token restarts the list without processing the items twice
+	resource = &pagedResource{pages: 3, expireAt: "3"}
+	gotNames = nil
+	err = ListInChunks(ctx, resource, metaV1.ListOptions{}, func(item *unstructured.Unstructured) error {
+		gotNames = append(gotNames, item.GetName())
+		return nil
+	})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotNames).To(gomega.Equal([]string{"node1", "node2", "node3"}))
+	g.Expect(len(resource.gotListOptions)).To(gomega.Equal(6))
+}
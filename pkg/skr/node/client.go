@@ -2,14 +2,13 @@ package node
 
 import (
 	"context"
-	"encoding/json"
 
+	skrcommons "github.com/kyma-incubator/metris/pkg/skr/commons"
 	corev1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Client struct {
@@ -17,11 +16,7 @@ type Client struct {
 }
 
 func (c Config) NewClient(kubeconfig string) (*Client, error) {
-	restClientConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
-	if err != nil {
-		return nil, err
-	}
-	dynamicClient, err := dynamic.NewForConfig(restClientConfig)
+	dynamicClient, err := c.Pool.Get(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
@@ -30,25 +25,32 @@ func (c Config) NewClient(kubeconfig string) (*Client, error) {
 }
 
 func (c Client) List(ctx context.Context) (*corev1.NodeList, error) {
-
-	nodesUnstructured, err := c.Resource.Namespace(corev1.NamespaceAll).List(ctx, metaV1.ListOptions{})
+	nodeList := &corev1.NodeList{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "NodeList",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+	}
+	err := skrcommons.ListInChunks(ctx, c.Resource.Namespace(corev1.NamespaceAll), metaV1.ListOptions{}, func(unstructuredNode *unstructured.Unstructured) error {
+		node, err := convertUnstructuredToNode(unstructuredNode)
+		if err != nil {
+			return err
+		}
+		nodeList.Items = append(nodeList.Items, *node)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return convertRuntimeListToNodeList(nodesUnstructured)
+	return nodeList, nil
 }
 
-func convertRuntimeListToNodeList(unstructuredNodesList *unstructured.UnstructuredList) (*corev1.NodeList, error) {
-	nodeList := new(corev1.NodeList)
-	nodeListBytes, err := unstructuredNodesList.MarshalJSON()
-	if err != nil {
+func convertUnstructuredToNode(unstructuredNode *unstructured.Unstructured) (*corev1.Node, error) {
+	node := new(corev1.Node)
+	if err := skrcommons.FromUnstructured(unstructuredNode, node); err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(nodeListBytes, nodeList)
-	if err != nil {
-		return nil, err
-	}
-	return nodeList, nil
+	return node, nil
 }
 
 func GroupVersionResource() schema.GroupVersionResource {
@@ -0,0 +1,62 @@
+package pv
+
+import (
+	"context"
+
+	skrcommons "github.com/kyma-incubator/metris/pkg/skr/commons"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+type Client struct {
+	Resource dynamic.NamespaceableResourceInterface
+}
+
+func (c Config) NewClient(kubeconfig string) (*Client, error) {
+	dynamicClient, err := c.Pool.Get(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	nsResourceClient := dynamicClient.Resource(GroupVersionResource())
+	return &Client{Resource: nsResourceClient}, nil
+}
+
+func (c Client) List(ctx context.Context) (*corev1.PersistentVolumeList, error) {
+	pvList := &corev1.PersistentVolumeList{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "PersistentVolumeList",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+	}
+	err := skrcommons.ListInChunks(ctx, c.Resource.Namespace(corev1.NamespaceAll), metaV1.ListOptions{}, func(unstructuredPV *unstructured.Unstructured) error {
+		pv, err := convertUnstructuredToPV(unstructuredPV)
+		if err != nil {
+			return err
+		}
+		pvList.Items = append(pvList.Items, *pv)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pvList, nil
+}
+
+func convertUnstructuredToPV(unstructuredPV *unstructured.Unstructured) (*corev1.PersistentVolume, error) {
+	pv := new(corev1.PersistentVolume)
+	if err := skrcommons.FromUnstructured(unstructuredPV, pv); err != nil {
+		return nil, err
+	}
+	return pv, nil
+}
+
+func GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Version:  corev1.SchemeGroupVersion.Version,
+		Group:    corev1.SchemeGroupVersion.Group,
+		Resource: "persistentvolumes",
+	}
+}
@@ -0,0 +1,56 @@
+package pv
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kyma-incubator/metris/pkg/gardener/commons"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	"github.com/onsi/gomega"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestList(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	pvList := metristesting.GetPersistentVolumes()
+	client, err := NewFakeClient(pvList)
+	g.Expect(err).Should(gomega.BeNil())
+
+	gotPVList, err := client.List(ctx)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(len(gotPVList.Items)).To(gomega.Equal(len(pvList.Items)))
+	sort.Slice(gotPVList.Items, func(i, j int) bool {
+		if gotPVList.Items[i].Name < gotPVList.Items[j].Name {
+			return true
+		}
+		return false
+	})
+	g.Expect(*gotPVList).To(gomega.Equal(*pvList))
+
+	// Delete all the pvs
+	for _, pv := range pvList.Items {
+		err := client.Resource.Delete(ctx, pv.Name, metaV1.DeleteOptions{})
+		g.Expect(err).Should(gomega.BeNil())
+	}
+
+	gotPVList, err = client.List(ctx)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(len(gotPVList.Items)).To(gomega.Equal(0))
+}
+
+func NewFakeClient(pvList *corev1.PersistentVolumeList) (*Client, error) {
+	scheme, err := commons.SetupSchemeOrDie()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, pvList)
+	nsResourceClient := dynamicClient.Resource(GroupVersionResource())
+	return &Client{Resource: nsResourceClient}, nil
+}
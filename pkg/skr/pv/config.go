@@ -0,0 +1,11 @@
+package pv
+
+import "github.com/kyma-incubator/metris/pkg/skr/clientpool"
+
+type ConfigInf interface {
+	NewClient(string) (*Client, error)
+}
+
+type Config struct {
+	Pool *clientpool.Pool
+}
@@ -0,0 +1,21 @@
+package pv
+
+import (
+	"github.com/kyma-incubator/metris/pkg/gardener/commons"
+	metristesting "github.com/kyma-incubator/metris/pkg/testing"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type FakePVClient struct{}
+
+func (fakePVClient FakePVClient) NewClient(string) (*Client, error) {
+	pvList := metristesting.GetPersistentVolumes()
+	scheme, err := commons.SetupSchemeOrDie()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, pvList)
+	nsResourceClient := dynamicClient.Resource(GroupVersionResource())
+	return &Client{Resource: nsResourceClient}, nil
+}
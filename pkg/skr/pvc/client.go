@@ -2,14 +2,13 @@ package pvc
 
 import (
 	"context"
-	"encoding/json"
 
+	skrcommons "github.com/kyma-incubator/metris/pkg/skr/commons"
 	corev1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Client struct {
@@ -17,11 +16,7 @@ type Client struct {
 }
 
 func (c Config) NewClient(kubeconfig string) (*Client, error) {
-	restClientConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
-	if err != nil {
-		return nil, err
-	}
-	dynamicClient, err := dynamic.NewForConfig(restClientConfig)
+	dynamicClient, err := c.Pool.Get(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
@@ -30,25 +25,32 @@ func (c Config) NewClient(kubeconfig string) (*Client, error) {
 }
 
 func (c Client) List(ctx context.Context) (*corev1.PersistentVolumeClaimList, error) {
-
-	unstructuredPVCList, err := c.Resource.Namespace(corev1.NamespaceAll).List(ctx, metaV1.ListOptions{})
+	pvcList := &corev1.PersistentVolumeClaimList{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "PersistentVolumeClaimList",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+	}
+	err := skrcommons.ListInChunks(ctx, c.Resource.Namespace(corev1.NamespaceAll), metaV1.ListOptions{}, func(unstructuredPVC *unstructured.Unstructured) error {
+		pvc, err := convertUnstructuredToPVC(unstructuredPVC)
+		if err != nil {
+			return err
+		}
+		pvcList.Items = append(pvcList.Items, *pvc)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return convertUnstructuredListToPVCList(unstructuredPVCList)
+	return pvcList, nil
 }
 
-func convertUnstructuredListToPVCList(unstructuredPVCList *unstructured.UnstructuredList) (*corev1.PersistentVolumeClaimList, error) {
-	pvcList := new(corev1.PersistentVolumeClaimList)
-	pvcListBytes, err := unstructuredPVCList.MarshalJSON()
-	if err != nil {
+func convertUnstructuredToPVC(unstructuredPVC *unstructured.Unstructured) (*corev1.PersistentVolumeClaim, error) {
+	pvc := new(corev1.PersistentVolumeClaim)
+	if err := skrcommons.FromUnstructured(unstructuredPVC, pvc); err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(pvcListBytes, pvcList)
-	if err != nil {
-		return nil, err
-	}
-	return pvcList, nil
+	return pvc, nil
 }
 
 func GroupVersionResource() schema.GroupVersionResource {
@@ -1,9 +1,11 @@
 package pvc
 
+import "github.com/kyma-incubator/metris/pkg/skr/clientpool"
+
 type ConfigInf interface {
 	NewClient(string) (*Client, error)
 }
 
 type Config struct {
-	kubeconfig string
+	Pool *clientpool.Pool
 }
@@ -2,14 +2,13 @@ package svc
 
 import (
 	"context"
-	"encoding/json"
 
+	skrcommons "github.com/kyma-incubator/metris/pkg/skr/commons"
 	corev1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Client struct {
@@ -17,11 +16,7 @@ type Client struct {
 }
 
 func (c Config) NewClient(kubeconfig string) (*Client, error) {
-	restClientConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
-	if err != nil {
-		return nil, err
-	}
-	dynamicClient, err := dynamic.NewForConfig(restClientConfig)
+	dynamicClient, err := c.Pool.Get(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
@@ -29,26 +24,37 @@ func (c Config) NewClient(kubeconfig string) (*Client, error) {
 	return &Client{Resource: nsResourceClient}, nil
 }
 
+// List lists the services of type LoadBalancer as only they are relevant for metering.
+// The API server does not support selecting services by type, hence they are filtered here instead.
 func (c Client) List(ctx context.Context) (*corev1.ServiceList, error) {
-
-	unstructuredSvcList, err := c.Resource.Namespace(corev1.NamespaceAll).List(ctx, metaV1.ListOptions{})
+	svcList := &corev1.ServiceList{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "ServiceList",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+	}
+	err := skrcommons.ListInChunks(ctx, c.Resource.Namespace(corev1.NamespaceAll), metaV1.ListOptions{}, func(unstructuredSvc *unstructured.Unstructured) error {
+		svc, err := convertUnstructuredToSvc(unstructuredSvc)
+		if err != nil {
+			return err
+		}
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			svcList.Items = append(svcList.Items, *svc)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return convertUnstructuredListToSVCList(unstructuredSvcList)
+	return svcList, nil
 }
 
-func convertUnstructuredListToSVCList(unstructuredSvcList *unstructured.UnstructuredList) (*corev1.ServiceList, error) {
-	svcList := new(corev1.ServiceList)
-	svcListBytes, err := unstructuredSvcList.MarshalJSON()
-	if err != nil {
+func convertUnstructuredToSvc(unstructuredSvc *unstructured.Unstructured) (*corev1.Service, error) {
+	svc := new(corev1.Service)
+	if err := skrcommons.FromUnstructured(unstructuredSvc, svc); err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(svcListBytes, svcList)
-	if err != nil {
-		return nil, err
-	}
-	return svcList, nil
+	return svc, nil
 }
 
 func GroupVersionResource() schema.GroupVersionResource {
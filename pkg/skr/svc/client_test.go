@@ -7,6 +7,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/kyma-incubator/metris/pkg/gardener/commons"
 	metristesting "github.com/kyma-incubator/metris/pkg/testing"
@@ -44,6 +46,30 @@ func TestList(t *testing.T) {
 	g.Expect(len(gotSvcList.Items)).To(gomega.Equal(0))
 }
 
+func TestListOnlyLoadBalancers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	svcList := metristesting.Get2SvcsOfDiffTypes()
+	scheme, err := commons.SetupSchemeOrDie()
+	g.Expect(err).Should(gomega.BeNil())
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, svcList)
+
+	// The services are filtered by type without a field selector
+	var gotFieldSelectors []string
+	dynamicClient.PrependReactor("list", "services", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		gotFieldSelectors = append(gotFieldSelectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+		return false, nil, nil
+	})
+	client := &Client{Resource: dynamicClient.Resource(GroupVersionResource())}
+
+	gotSvcList, err := client.List(ctx)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(gotFieldSelectors).To(gomega.Equal([]string{""}))
+	g.Expect(len(gotSvcList.Items)).To(gomega.Equal(1))
+	g.Expect(gotSvcList.Items[0].Name).To(gomega.Equal("svc2"))
+}
+
 func NewFakeClient(svcList *corev1.ServiceList) (*Client, error) {
 	scheme, err := commons.SetupSchemeOrDie()
 	if err != nil {
@@ -34,10 +34,16 @@ const (
 
 type NewRuntimeOpts func(*kebruntime.RuntimeDTO)
 
+// NewRuntimeID returns the runtime ID which NewRuntimesDTO uses for the runtime of a subaccount
+func NewRuntimeID(subAccountID string) string {
+	return fmt.Sprintf("runtime-%s", subAccountID)
+}
+
 func NewRuntimesDTO(subAccountID string, shootName string, opts ...NewRuntimeOpts) kebruntime.RuntimeDTO {
 	runtime := kebruntime.RuntimeDTO{
 		ShootName:    shootName,
 		SubAccountID: subAccountID,
+		RuntimeID:    NewRuntimeID(subAccountID),
 		Status: kebruntime.RuntimeStatus{
 			Provisioning: &kebruntime.Operation{
 				State: "succeeded",
@@ -151,6 +157,9 @@ func WithAzureProviderAndStandardD8V3VMs(shoot *gardencorev1beta1.Shoot) {
 						Name: "gardenlinux",
 					},
 				},
+				Minimum: 2,
+				Maximum: 5,
+				Zones:   []string{"1", "2"},
 			},
 		},
 	}
@@ -232,6 +241,7 @@ func GetNode(name, vmType string) corev1.Node {
 			Labels: map[string]string{
 				"node.kubernetes.io/instance-type": vmType,
 				"node.kubernetes.io/role":          "node",
+				"worker.gardener.cloud/pool":       "cpu-worker-0",
 			},
 		},
 	}
@@ -290,6 +300,8 @@ func Get3PVCs() *corev1.PersistentVolumeClaimList {
 
 func GetPVCs() *corev1.PersistentVolumeClaimList {
 	pv10GInFooNs := GetPV("foo-10G", "foo", "10Gi")
+	// The PVC is bound to the PV of GetPersistentVolumes
+	pv10GInFooNs.Spec.VolumeName = "pv-bound-10G"
 	pv20GInBarNs := GetPV("foo-20G", "bar", "20Gi")
 
 	return &corev1.PersistentVolumeClaimList{
@@ -333,6 +345,51 @@ func GetPV(name, namespace, capacity string) *corev1.PersistentVolumeClaim {
 	}
 }
 
+// GetPersistentVolumes returns a PV which is bound and a PV which is released but retained
+func GetPersistentVolumes() *corev1.PersistentVolumeList {
+	bound := GetPersistentVolume("pv-bound-10G", "10Gi", corev1.VolumeBound, corev1.PersistentVolumeReclaimDelete)
+	released := GetPersistentVolume("pv-released-5G", "5Gi", corev1.VolumeReleased, corev1.PersistentVolumeReclaimRetain)
+	return &corev1.PersistentVolumeList{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "PersistentVolumeList",
+			APIVersion: "v1",
+		},
+		Items: []corev1.PersistentVolume{*bound, *released},
+	}
+}
+
+func GetPersistentVolume(name, capacity string, phase corev1.PersistentVolumePhase, reclaimPolicy corev1.PersistentVolumeReclaimPolicy) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "PersistentVolume",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				"pv.kubernetes.io/provisioned-by": "disk.csi.azure.com",
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity: corev1.ResourceList{
+				"storage": resource.MustParse(capacity),
+			},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:           "disk.csi.azure.com",
+					VolumeHandle:     name,
+					VolumeAttributes: map[string]string{"skuName": "StandardSSD_LRS"},
+				},
+			},
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			StorageClassName:              "default",
+		},
+		Status: corev1.PersistentVolumeStatus{
+			Phase: phase,
+		},
+	}
+}
+
 func Get2SvcsOfDiffTypes() *corev1.ServiceList {
 	svc1 := GetSvc("svc1", "foo", WithClusterIP)
 	svc2 := GetSvc("svc2", "foo", WithLoadBalancer)
@@ -394,6 +451,9 @@ func WithLoadBalancer(service *corev1.Service) {
 	service.Spec = corev1.ServiceSpec{
 		Type: "LoadBalancer",
 	}
+	service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
+		{Hostname: fmt.Sprintf("%s.%s.lb.example.com", service.Name, service.Namespace)},
+	}
 }
 
 func NewSecret(shootName, kubeconfigVal string) *corev1.Secret {